@@ -3,30 +3,48 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"simple-chat/internal/clients/sso"
 	ssogrpc "simple-chat/internal/clients/sso/grpc"
+	ssojwt "simple-chat/internal/clients/sso/jwt"
+	"simple-chat/internal/clients/sso/static"
 	"simple-chat/internal/config"
+	adminHandler "simple-chat/internal/handlers/admin"
 	"simple-chat/internal/handlers/auth"
 	chatHandler "simple-chat/internal/handlers/chat"
 	messageHandler "simple-chat/internal/handlers/message"
+	reportHandler "simple-chat/internal/handlers/report"
+	userHandler "simple-chat/internal/handlers/user"
+	"simple-chat/internal/lib/admin"
+	"simple-chat/internal/lib/avatar"
+	"simple-chat/internal/lib/content"
 	"simple-chat/internal/lib/logger/sl"
 	mwLogger "simple-chat/internal/lib/middleware"
+	"simple-chat/internal/lib/moderation"
+	"simple-chat/internal/lib/ratelimit"
 	"simple-chat/internal/logger"
 	chat_service "simple-chat/internal/services/chat"
 	message_service "simple-chat/internal/services/message"
+	report_service "simple-chat/internal/services/report"
+	user_service "simple-chat/internal/services/user"
 	"simple-chat/internal/storage/chat"
+	"simple-chat/internal/storage/invite"
 	"simple-chat/internal/storage/message"
 	"simple-chat/internal/storage/postgresql"
+	"simple-chat/internal/storage/report"
+	"simple-chat/internal/storage/scheduled"
+	"simple-chat/internal/storage/user"
 	"syscall"
-	"time"
 
 	"github.com/go-chi/cors"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
@@ -46,18 +64,70 @@ func main() {
 
 	chatDB := chat.NewChatDB(log)
 	messageDB := message.NewMessageDB(log)
+	inviteDB := invite.NewInviteDB(log)
+	userDB := user.NewUserDB(log)
+	reportDB := report.NewReportDB(log)
+	scheduledDB := scheduled.NewScheduledDB(log)
 
-	chatService := chat_service.NewChatService(log, chatDB, dbPool)
-	messageService := message_service.NewMessageServices(log, messageDB, dbPool)
-	ssoClient, err := ssogrpc.NewClient(log, cfg.SSOClient)
+	chatService := chat_service.NewChatService(log, chatDB, userDB, messageDB, inviteDB, dbPool)
+	moderator, err := moderation.New(cfg.Moderation)
 	if err != nil {
-		log.Error("failed to create sso client", sl.Err(err))
+		log.Error("failed to configure moderation", sl.Err(err))
 		os.Exit(1)
 	}
+	messageService := message_service.NewMessageServices(log, messageDB, chatDB, reportDB, userDB, scheduledDB, content.NewPipeline(cfg.MessageContent), moderator, dbPool)
+	userService := user_service.NewUserService(log, userDB, dbPool)
+	reportService := report_service.NewReportService(log, reportDB, chatDB, messageDB, dbPool)
+
+	var provider sso.Provider
+	switch cfg.Auth.Provider {
+	case config.AuthProviderSSO:
+		provider, err = ssogrpc.NewClient(log, cfg.SSOClient, userService)
+		if err != nil {
+			log.Error("failed to create sso client", sl.Err(err))
+			os.Exit(1)
+		}
+	case config.AuthProviderStatic:
+		provider, err = static.New(log, cfg.Auth.Static)
+		if err != nil {
+			log.Error("failed to create static auth provider", sl.Err(err))
+			os.Exit(1)
+		}
+	default:
+		log.Error("unknown auth provider", slog.String("provider", cfg.Auth.Provider))
+		os.Exit(1)
+	}
+	log.Info("auth provider selected", slog.String("provider", cfg.Auth.Provider))
+
+	var verifier mwLogger.TokenVerifier = provider
+	switch cfg.Auth.Strategy {
+	case config.AuthStrategySSO:
+	case config.AuthStrategyJWT:
+		verifier, err = ssojwt.NewVerifier(log, cfg.Auth.JWT, provider, userService, cfg.SSOClient.ProfileCache)
+		if err != nil {
+			log.Error("failed to create jwt verifier", sl.Err(err))
+			os.Exit(1)
+		}
+	default:
+		log.Error("unknown auth strategy", slog.String("strategy", cfg.Auth.Strategy))
+		os.Exit(1)
+	}
+	log.Info("auth strategy selected", slog.String("strategy", cfg.Auth.Strategy))
+
+	var ssoAdmins admin.SSOChecker
+	if checker, ok := provider.(admin.SSOChecker); ok && cfg.Admin.SSOCheck {
+		ssoAdmins = checker
+	}
+	admins := admin.NewChecker(cfg.Admin, ssoAdmins, cfg.AppID)
 
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
-	router.Use(middleware.RealIP)
+	realIP, err := mwLogger.RealIP(cfg.HTTPServer.TrustedProxies)
+	if err != nil {
+		log.Error("failed to configure trusted proxies", sl.Err(err))
+		os.Exit(1)
+	}
+	router.Use(realIP)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
 	router.Use(mwLogger.New(log))
@@ -73,9 +143,36 @@ func main() {
 	}))
 	log.Info("cors successfully conected")
 
-	router.Route("/auth", auth.AddAuthHandler(ssoClient, log, cfg.AppID))
-	router.Route("/chat", chatHandler.AddChatHandler(log, chatService, messageService, ssoClient, cfg.AppID))
-	router.Route("/message", messageHandler.AddMessageHandler(log, messageService, ssoClient, cfg.AppID))
+	authThrottle := ratelimit.NewAuthThrottle(ratelimit.NewMemoryLimiter(), cfg.AuthThrottle)
+
+	router.Route("/auth", auth.AddAuthHandler(provider, verifier, authThrottle, log, cfg.AppID))
+	hub := chatHandler.NewHub(log)
+	avatars, err := avatar.NewStore(cfg.Avatars)
+	if err != nil {
+		log.Error("failed to create avatar store", sl.Err(err))
+		os.Exit(1)
+	}
+	messageLimiter := ratelimit.NewMessagePolicy(ratelimit.NewMemoryLimiter(), cfg.RateLimit)
+
+	workers, stopWorkers := context.WithCancel(context.Background())
+	scheduler := message_service.NewScheduler(log, messageService, hub, cfg.Scheduler)
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		scheduler.Run(workers)
+	}()
+	sweeper := message_service.NewSweeper(log, messageService, hub, cfg.Sweeper)
+	sweeperDone := make(chan struct{})
+	go func() {
+		defer close(sweeperDone)
+		sweeper.Run(workers)
+	}()
+
+	router.Route("/chat", chatHandler.AddChatHandler(log, chatService, messageService, provider, messageLimiter, verifier, hub, avatars, cfg.Websocket, cfg.AppID))
+	router.Route("/message", messageHandler.AddMessageHandler(log, messageService, provider, messageLimiter, verifier, cfg.AppID))
+	router.Route("/report", reportHandler.AddReportHandler(log, reportService, verifier, cfg.AppID))
+	router.Route("/user", userHandler.AddUserHandler(log, userService, verifier, cfg.AppID))
+	router.Route("/admin", adminHandler.AddAdminHandler(log, reportService, userService, hub, verifier, admins, cfg.AppID))
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", cfg.HTTPServer.Host, cfg.HTTPServer.Port),
@@ -93,12 +190,63 @@ func main() {
 		}
 	}()
 
+	metricsRouter := chi.NewRouter()
+	metricsRouter.Handle("/metrics", promhttp.Handler())
+	metricsSrv := &http.Server{
+		Addr:         fmt.Sprintf("%s:%d", cfg.Metrics.Host, cfg.Metrics.Port),
+		Handler:      metricsRouter,
+		ReadTimeout:  cfg.HTTPServer.Timeout,
+		WriteTimeout: cfg.HTTPServer.Timeout,
+		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+	}
+
+	go func() {
+		log.Info("starting metrics server", slog.String("addr", metricsSrv.Addr))
+		if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("failed to listen and serve metrics", sl.Err(err))
+			os.Exit(1)
+		}
+	}()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	stopSignal := <-stop
 	log.Info("stoppping server", slog.String("signal", stopSignal.String()))
-	ctx, close := context.WithTimeout(context.Background(), time.Minute)
+	ctx, close := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
 	defer close()
-	srv.Shutdown(ctx)
+
+	// Background workers stop first so that they do not write to the hub or
+	// the database while those are shut down.
+	stopWorkers()
+	<-schedulerDone
+	<-sweeperDone
+	log.Info("background workers were stopped")
+
+	// Hijacked websocket connections are not tracked by srv.Shutdown, so the
+	// hub is drained first, then the database and SSO are closed once nothing
+	// can write to them anymore.
+	if err := hub.Shutdown(ctx); err != nil {
+		log.Error("failed to drain websocket connections", sl.Err(err))
+	}
+	log.Info("websocket connections were closed")
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Error("failed to stop server", sl.Err(err))
+	}
 	log.Info("server was stopped")
+
+	if err := metricsSrv.Shutdown(ctx); err != nil {
+		log.Error("failed to stop metrics server", sl.Err(err))
+	}
+	log.Info("metrics server was stopped")
+
+	dbPool.Close()
+	log.Info("database was stopped")
+
+	if closer, ok := provider.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Error("failed to close auth provider", sl.Err(err))
+		}
+	}
+	log.Info("auth provider was stopped")
 }
@@ -1,24 +1,58 @@
 package grpc
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log/slog"
+	"simple-chat/internal/clients/sso"
 	"simple-chat/internal/config"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/cache"
 	"simple-chat/internal/lib/logger/sl"
+	"simple-chat/internal/lib/metrics"
+	"strconv"
+	"time"
 
 	ssov1 "github.com/bordviz/sso-protos/gen/go/sso"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
 	grpcRetry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
+var ErrUsersUnavailable = errors.New("failed to resolve users")
+
 type Client struct {
-	Api ssov1.AuthClient
+	Api       ssov1.AuthClient
+	cc        *grpc.ClientConn
+	log       *slog.Logger
+	directory UserDirectory
+	profiles  *cache.Cache[int64, models.User]
+	tokens    *cache.Cache[string, tokenResult]
+	tokenCfg  config.TokenCache
+}
+
+type tokenResult struct {
+	user models.User
+	err  error
+}
+
+// UserDirectory stores the profiles of users seen by the chat. It resolves
+// user IDs that SSO cannot answer and supplies names and emails.
+type UserDirectory interface {
+	SaveUser(ctx context.Context, user models.User) error
+	GetUsers(ctx context.Context, userIDs []int64) (map[int64]models.User, error)
 }
 
 func NewClient(
 	log *slog.Logger,
 	cfg config.SSOClient,
+	directory UserDirectory,
 ) (*Client, error) {
 	const op = "client.sso.grpc.NewClient"
 
@@ -41,6 +75,295 @@ func NewClient(
 	}
 
 	return &Client{
-		Api: ssov1.NewAuthClient(cc),
+		Api:       ssov1.NewAuthClient(cc),
+		cc:        cc,
+		log:       log,
+		directory: directory,
+		profiles:  cache.New[int64, models.User](cfg.ProfileCache.TTL, cfg.ProfileCache.Size),
+		tokens:    cache.New[string, tokenResult](cfg.TokenCache.TTL, cfg.TokenCache.Size),
+		tokenCfg:  cfg.TokenCache,
 	}, nil
 }
+
+func (c *Client) Close() error {
+	return c.cc.Close()
+}
+
+func (c *Client) Register(ctx context.Context, email string, password string, name string) (int64, error) {
+	resp, err := c.Api.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+		Name:     name,
+	})
+	if err != nil {
+		return 0, mapError(err, map[codes.Code]error{
+			codes.AlreadyExists:   sso.ErrUserExists,
+			codes.InvalidArgument: sso.ErrInvalidArgument,
+		})
+	}
+
+	return resp.GetUserId(), nil
+}
+
+func (c *Client) Login(ctx context.Context, email string, password string, appID int32) (models.Tokens, error) {
+	resp, err := c.Api.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	if err != nil {
+		// SSO reports wrong credentials as InvalidArgument.
+		return models.Tokens{}, mapError(err, map[codes.Code]error{
+			codes.InvalidArgument:  sso.ErrInvalidCredentials,
+			codes.Unauthenticated:  sso.ErrInvalidCredentials,
+			codes.NotFound:         sso.ErrInvalidCredentials,
+			codes.PermissionDenied: sso.ErrInvalidCredentials,
+		})
+	}
+
+	return models.Tokens{
+		AccessToken:  resp.GetAccessToken(),
+		RefreshToken: resp.GetRefreshToken(),
+	}, nil
+}
+
+func (c *Client) RefreshToken(ctx context.Context, token string, appID int32) (models.Tokens, error) {
+	resp, err := c.Api.RefreshToken(ctx, &ssov1.RefreshTokenRequest{
+		Token: token,
+		AppId: appID,
+	})
+	if err != nil {
+		return models.Tokens{}, mapError(err, map[codes.Code]error{
+			codes.InvalidArgument:  sso.ErrInvalidToken,
+			codes.Unauthenticated:  sso.ErrInvalidToken,
+			codes.NotFound:         sso.ErrInvalidToken,
+			codes.PermissionDenied: sso.ErrInvalidToken,
+		})
+	}
+
+	return models.Tokens{
+		AccessToken:  resp.GetAccessToken(),
+		RefreshToken: resp.GetRefreshToken(),
+	}, nil
+}
+
+// CurrentUser returns the owner of the token and records their profile in
+// the user directory. Validated tokens are cached for the configured TTL, but
+// never past their own expiry, and rejected ones for the shorter negative TTL.
+func (c *Client) CurrentUser(ctx context.Context, token string, appID int32) (models.User, error) {
+	const op = "client.sso.grpc.CurrentUser"
+
+	key := tokenKey(token, appID)
+	if cached, ok := c.tokens.Get(key); ok {
+		if cached.err != nil {
+			metrics.TokenCacheRequests.WithLabelValues(metrics.ResultNegativeHit).Inc()
+			return models.User{}, cached.err
+		}
+		metrics.TokenCacheRequests.WithLabelValues(metrics.ResultHit).Inc()
+		return cached.user, nil
+	}
+	metrics.TokenCacheRequests.WithLabelValues(metrics.ResultMiss).Inc()
+
+	resp, err := c.Api.CurrentUser(ctx, &ssov1.CurrentUserRequest{
+		Token: token,
+		AppId: appID,
+	})
+	if err != nil {
+		if !isRejected(err) {
+			return models.User{}, fmt.Errorf("%w: %w", sso.ErrUnavailable, err)
+		}
+		err = fmt.Errorf("%w: %w", sso.ErrInvalidToken, err)
+		c.tokens.SetWithTTL(key, tokenResult{err: err}, c.tokenCfg.NegativeTTL)
+		return models.User{}, err
+	}
+
+	user := models.User{
+		UserID: resp.GetUserId(),
+		Email:  resp.GetEmail(),
+		Name:   resp.GetName(),
+	}
+
+	c.tokens.SetWithTTL(key, tokenResult{user: user}, c.tokenTTL(token))
+
+	// The directory is only written when the profile is new or has changed
+	// since it was last cached, keeping the write off most token misses.
+	cached, ok := c.profiles.Get(user.UserID)
+	c.profiles.Set(user.UserID, user)
+
+	if c.directory != nil && (!ok || cached != user) {
+		if err := c.directory.SaveUser(ctx, user); err != nil {
+			c.log.Error("failed to save user to directory", sl.OpErr(op, err))
+		}
+	}
+
+	return user, nil
+}
+
+// IsAdmin reports whether SSO considers the user an administrator of the
+// app. Unknown users are not admins.
+func (c *Client) IsAdmin(ctx context.Context, appID int32, userID int64) (bool, error) {
+	resp, err := c.Api.IsAdmin(
+		ctx,
+		&ssov1.IsAdminRequest{UserId: userID, AppId: appID},
+		grpcRetry.WithCodes(codes.Aborted, codes.DeadlineExceeded),
+	)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return false, nil
+		}
+		return false, mapError(err, nil)
+	}
+
+	return resp.GetIsAdmin(), nil
+}
+
+// GetUsers resolves the given user IDs and returns the users that exist.
+// Resolved users are cached, so repeated IDs cost at most one lookup per TTL.
+func (c *Client) GetUsers(ctx context.Context, appID int32, userIDs []int64) (map[int64]models.User, error) {
+	users := make(map[int64]models.User, len(userIDs))
+
+	var missing []int64
+	for userID := range distinct(userIDs) {
+		if user, ok := c.profiles.Get(userID); ok {
+			users[userID] = user
+			continue
+		}
+		missing = append(missing, userID)
+	}
+	if len(missing) == 0 {
+		return users, nil
+	}
+
+	resolved, err := c.resolveUsers(ctx, appID, missing)
+	if err != nil {
+		return nil, err
+	}
+	for userID, user := range resolved {
+		c.profiles.Set(userID, user)
+		users[userID] = user
+	}
+
+	return users, nil
+}
+
+// resolveUsers asks SSO whether the accounts exist. SSO has no profile
+// lookup, so IsAdmin is used as an existence probe: NotFound and
+// InvalidArgument mean the user is unknown, any other error means SSO could
+// not answer. IDs SSO could not answer fall back to the user directory, which
+// also fills in names and emails for the users it knows.
+func (c *Client) resolveUsers(ctx context.Context, appID int32, userIDs []int64) (map[int64]models.User, error) {
+	const op = "client.sso.grpc.resolveUsers"
+
+	users := make(map[int64]models.User, len(userIDs))
+	var known, unanswered []int64
+
+	for _, userID := range userIDs {
+		_, err := c.Api.IsAdmin(
+			ctx,
+			&ssov1.IsAdminRequest{UserId: userID, AppId: appID},
+			grpcRetry.WithCodes(codes.Aborted, codes.DeadlineExceeded),
+		)
+		switch status.Code(err) {
+		case codes.OK:
+			users[userID] = models.User{UserID: userID}
+			known = append(known, userID)
+		case codes.NotFound, codes.InvalidArgument:
+		default:
+			c.log.Error("failed to resolve user in sso", slog.Int64("user_id", userID), sl.OpErr(op, err))
+			unanswered = append(unanswered, userID)
+		}
+	}
+
+	lookup := append(known, unanswered...)
+	if len(lookup) == 0 {
+		return users, nil
+	}
+	if c.directory == nil {
+		if len(unanswered) > 0 {
+			return nil, ErrUsersUnavailable
+		}
+		return users, nil
+	}
+
+	profiles, err := c.directory.GetUsers(ctx, lookup)
+	if err != nil {
+		c.log.Error("failed to get users from directory", sl.OpErr(op, err))
+		if len(unanswered) > 0 {
+			return nil, ErrUsersUnavailable
+		}
+		return users, nil
+	}
+	for id, user := range profiles {
+		users[id] = user
+	}
+
+	for _, userID := range unanswered {
+		if _, ok := users[userID]; !ok {
+			return nil, ErrUsersUnavailable
+		}
+	}
+
+	return users, nil
+}
+
+// tokenTTL caps the cache TTL at the token's exp claim. SSO has already
+// validated the token, so the claims are read without verifying the
+// signature; tokens without a readable exp keep the configured TTL.
+func (c *Client) tokenTTL(token string) time.Duration {
+	ttl := c.tokenCfg.TTL
+
+	claims := jwtv5.MapClaims{}
+	if _, _, err := jwtv5.NewParser().ParseUnverified(token, claims); err != nil {
+		return ttl
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return ttl
+	}
+
+	if until := time.Until(exp.Time); until < ttl {
+		return until
+	}
+	return ttl
+}
+
+func tokenKey(token string, appID int32) string {
+	sum := sha256.Sum256([]byte(strconv.Itoa(int(appID)) + ":" + token))
+	return hex.EncodeToString(sum[:])
+}
+
+// isRejected reports whether SSO refused the token itself, as opposed to
+// failing to answer; only rejections are negatively cached.
+func isRejected(err error) bool {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied, codes.InvalidArgument, codes.NotFound:
+		return true
+	default:
+		return false
+	}
+}
+
+// mapError translates a gRPC status into the matching sso error, keeping the
+// status wrapped for logging. Codes missing from rejected are treated as SSO
+// being unavailable when they are transient and returned unchanged otherwise.
+func mapError(err error, rejected map[codes.Code]error) error {
+	code := status.Code(err)
+	if target, ok := rejected[code]; ok {
+		return fmt.Errorf("%w: %w", target, err)
+	}
+
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Canceled:
+		return fmt.Errorf("%w: %w", sso.ErrUnavailable, err)
+	default:
+		return err
+	}
+}
+
+func distinct(ids []int64) map[int64]struct{} {
+	set := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
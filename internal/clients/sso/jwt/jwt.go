@@ -0,0 +1,196 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"log/slog"
+	"simple-chat/internal/config"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/cache"
+	"simple-chat/internal/lib/logger/sl"
+
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrTokenExpired  = errors.New("token expired")
+	ErrAppIDMismatch = errors.New("token was issued for another app")
+	ErrUnknownKey    = errors.New("token signing key is unknown")
+)
+
+// TokenVerifier resolves the owner of a token. It is implemented by the SSO
+// gRPC client and used as the fallback for tokens that cannot be verified
+// locally.
+type TokenVerifier interface {
+	CurrentUser(ctx context.Context, token string, appID int32) (models.User, error)
+}
+
+// UserDirectory records the profiles of users that authenticate with the
+// chat, as the SSO gRPC client does for the tokens it validates.
+type UserDirectory interface {
+	SaveUser(ctx context.Context, user models.User) error
+}
+
+// Verifier checks SSO issued JWTs against locally configured public keys,
+// without a round trip to SSO.
+type Verifier struct {
+	log       *slog.Logger
+	keys      map[string]crypto.PublicKey
+	parser    *jwtv5.Parser
+	fallback  TokenVerifier
+	directory UserDirectory
+	profiles  *cache.Cache[int64, models.User]
+}
+
+func NewVerifier(
+	log *slog.Logger,
+	cfg config.JWT,
+	fallback TokenVerifier,
+	directory UserDirectory,
+	profiles config.ProfileCache,
+) (*Verifier, error) {
+	const op = "client.sso.jwt.NewVerifier"
+
+	keys := make(map[string]crypto.PublicKey)
+
+	if cfg.JWKSPath != "" {
+		set, err := loadJWKS(cfg.JWKSPath)
+		if err != nil {
+			log.Error("failed to load jwks", sl.OpErr(op, err))
+			return nil, err
+		}
+		for kid, key := range set {
+			keys[kid] = key
+		}
+	}
+
+	if cfg.PublicKeyPath != "" {
+		key, err := loadPublicKey(cfg.PublicKeyPath)
+		if err != nil {
+			log.Error("failed to load public key", sl.OpErr(op, err))
+			return nil, err
+		}
+		keys[""] = key
+	}
+
+	if len(keys) == 0 {
+		log.Error("failed to create jwt verifier", sl.OpErr(op, ErrNoKeys))
+		return nil, ErrNoKeys
+	}
+
+	v := &Verifier{
+		log:  log,
+		keys: keys,
+		parser: jwtv5.NewParser(
+			jwtv5.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+			jwtv5.WithExpirationRequired(),
+			jwtv5.WithLeeway(cfg.Leeway),
+		),
+		directory: directory,
+		profiles:  cache.New[int64, models.User](profiles.TTL, profiles.Size),
+	}
+	if cfg.Fallback {
+		v.fallback = fallback
+	}
+
+	return v, nil
+}
+
+// CurrentUser verifies the token signature, expiry and app_id claim locally
+// and records the owner's profile in the user directory. Tokens signed by an
+// unknown key or otherwise unverifiable are passed to the fallback when it is
+// configured; expired tokens and tokens for another app are always rejected.
+func (v *Verifier) CurrentUser(ctx context.Context, token string, appID int32) (models.User, error) {
+	const op = "client.sso.jwt.CurrentUser"
+
+	user, err := v.verify(token, appID)
+	if err == nil {
+		v.saveUser(ctx, user)
+		return user, nil
+	}
+
+	if v.fallback != nil && !errors.Is(err, ErrTokenExpired) && !errors.Is(err, ErrAppIDMismatch) {
+		v.log.Debug("local token verification failed, falling back to sso", sl.OpErr(op, err))
+		return v.fallback.CurrentUser(ctx, token, appID)
+	}
+
+	return models.User{}, err
+}
+
+// saveUser writes the profile to the directory when it is new or has changed
+// since it was last cached, keeping the write off most requests.
+func (v *Verifier) saveUser(ctx context.Context, user models.User) {
+	const op = "client.sso.jwt.saveUser"
+
+	if v.directory == nil {
+		return
+	}
+	if cached, ok := v.profiles.Get(user.UserID); ok && cached == user {
+		return
+	}
+
+	if err := v.directory.SaveUser(ctx, user); err != nil {
+		v.log.Error("failed to save user to directory", sl.OpErr(op, err))
+		return
+	}
+	v.profiles.Set(user.UserID, user)
+}
+
+func (v *Verifier) verify(token string, appID int32) (models.User, error) {
+	claims := jwtv5.MapClaims{}
+
+	_, err := v.parser.ParseWithClaims(token, claims, v.keyFunc)
+	if err != nil {
+		switch {
+		case errors.Is(err, jwtv5.ErrTokenExpired):
+			return models.User{}, ErrTokenExpired
+		case errors.Is(err, ErrUnknownKey):
+			return models.User{}, ErrUnknownKey
+		default:
+			return models.User{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+	}
+
+	tokenAppID, ok := claims["app_id"].(float64)
+	if !ok {
+		return models.User{}, fmt.Errorf("%w: app_id claim is missing", ErrInvalidToken)
+	}
+	if int32(tokenAppID) != appID {
+		return models.User{}, ErrAppIDMismatch
+	}
+
+	userID, ok := claims["uid"].(float64)
+	if !ok {
+		return models.User{}, fmt.Errorf("%w: uid claim is missing", ErrInvalidToken)
+	}
+
+	email, _ := claims["email"].(string)
+	name, _ := claims["name"].(string)
+
+	return models.User{
+		UserID: int64(userID),
+		Email:  email,
+		Name:   name,
+	}, nil
+}
+
+func (v *Verifier) keyFunc(token *jwtv5.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok := v.keys[kid]; ok {
+			return key, nil
+		}
+		if key, ok := v.keys[""]; ok {
+			return key, nil
+		}
+		return nil, ErrUnknownKey
+	}
+
+	keys := make([]jwtv5.VerificationKey, 0, len(v.keys))
+	for _, key := range v.keys {
+		keys = append(keys, key)
+	}
+	return jwtv5.VerificationKeySet{Keys: keys}, nil
+}
@@ -0,0 +1,250 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"simple-chat/internal/config"
+	"simple-chat/internal/domain/models"
+	"testing"
+	"time"
+
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+)
+
+const testAppID = 1
+
+var errFallback = errors.New("fallback called")
+
+type fallbackVerifier struct {
+	calls int
+}
+
+func (f *fallbackVerifier) CurrentUser(ctx context.Context, token string, appID int32) (models.User, error) {
+	f.calls++
+	return models.User{}, errFallback
+}
+
+type directory struct {
+	saved []models.User
+}
+
+func (d *directory) SaveUser(ctx context.Context, user models.User) error {
+	d.saved = append(d.saved, user)
+	return nil
+}
+
+func writePublicKey(t *testing.T, key ed25519.PublicKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write public key: %v", err)
+	}
+	return path
+}
+
+func newTestVerifier(t *testing.T, public ed25519.PublicKey, fallback TokenVerifier, dir UserDirectory) *Verifier {
+	t.Helper()
+
+	cfg := config.JWT{PublicKeyPath: writePublicKey(t, public), Fallback: fallback != nil}
+	v, err := NewVerifier(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg,
+		fallback,
+		dir,
+		config.ProfileCache{TTL: time.Minute, Size: 10},
+	)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	return v
+}
+
+func validClaims() jwtv5.MapClaims {
+	return jwtv5.MapClaims{
+		"uid":    float64(42),
+		"email":  "user@example.com",
+		"name":   "User",
+		"app_id": float64(testAppID),
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func sign(t *testing.T, method jwtv5.SigningMethod, key any, claims jwtv5.MapClaims) string {
+	t.Helper()
+
+	token, err := jwtv5.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestVerifierCurrentUser(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	_, otherPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	withClaims := func(change func(jwtv5.MapClaims)) jwtv5.MapClaims {
+		claims := validClaims()
+		change(claims)
+		return claims
+	}
+
+	tests := []struct {
+		name         string
+		token        string
+		fallback     bool
+		wantErr      error
+		wantFallback bool
+	}{
+		{
+			name:  "valid token",
+			token: sign(t, jwtv5.SigningMethodEdDSA, private, validClaims()),
+		},
+		{
+			name:    "expired token",
+			token:   sign(t, jwtv5.SigningMethodEdDSA, private, withClaims(func(c jwtv5.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:     "expired token is not passed to the fallback",
+			token:    sign(t, jwtv5.SigningMethodEdDSA, private, withClaims(func(c jwtv5.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			fallback: true,
+			wantErr:  ErrTokenExpired,
+		},
+		{
+			name:    "missing exp",
+			token:   sign(t, jwtv5.SigningMethodEdDSA, private, withClaims(func(c jwtv5.MapClaims) { delete(c, "exp") })),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "token for another app",
+			token:   sign(t, jwtv5.SigningMethodEdDSA, private, withClaims(func(c jwtv5.MapClaims) { c["app_id"] = float64(testAppID + 1) })),
+			wantErr: ErrAppIDMismatch,
+		},
+		{
+			name:     "token for another app is not passed to the fallback",
+			token:    sign(t, jwtv5.SigningMethodEdDSA, private, withClaims(func(c jwtv5.MapClaims) { c["app_id"] = float64(testAppID + 1) })),
+			fallback: true,
+			wantErr:  ErrAppIDMismatch,
+		},
+		{
+			name:    "missing app_id",
+			token:   sign(t, jwtv5.SigningMethodEdDSA, private, withClaims(func(c jwtv5.MapClaims) { delete(c, "app_id") })),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "missing uid",
+			token:   sign(t, jwtv5.SigningMethodEdDSA, private, withClaims(func(c jwtv5.MapClaims) { delete(c, "uid") })),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "symmetric algorithm",
+			token:   sign(t, jwtv5.SigningMethodHS256, []byte(public), validClaims()),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "unsigned token",
+			token:   sign(t, jwtv5.SigningMethodNone, jwtv5.UnsafeAllowNoneSignatureType, validClaims()),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "signed by another key",
+			token:   sign(t, jwtv5.SigningMethodEdDSA, otherPrivate, validClaims()),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:         "unverifiable token is passed to the fallback",
+			token:        sign(t, jwtv5.SigningMethodEdDSA, otherPrivate, validClaims()),
+			fallback:     true,
+			wantErr:      errFallback,
+			wantFallback: true,
+		},
+		{
+			name:    "malformed token",
+			token:   "not.a.token",
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				fallback *fallbackVerifier
+				verifier TokenVerifier
+			)
+			if tt.fallback {
+				fallback = &fallbackVerifier{}
+				verifier = fallback
+			}
+			v := newTestVerifier(t, public, verifier, nil)
+
+			user, err := v.CurrentUser(context.Background(), tt.token, testAppID)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				want := models.User{UserID: 42, Email: "user@example.com", Name: "User"}
+				if user != want {
+					t.Errorf("got user %+v, want %+v", user, want)
+				}
+			}
+
+			if called := fallback != nil && fallback.calls > 0; called != tt.wantFallback {
+				t.Errorf("fallback called = %v, want %v", called, tt.wantFallback)
+			}
+		})
+	}
+}
+
+func TestVerifierSavesChangedProfiles(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	dir := &directory{}
+	v := newTestVerifier(t, public, nil, dir)
+
+	token := sign(t, jwtv5.SigningMethodEdDSA, private, validClaims())
+	for range 3 {
+		if _, err := v.CurrentUser(context.Background(), token, testAppID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(dir.saved) != 1 {
+		t.Fatalf("saved %d profiles for an unchanged user, want 1", len(dir.saved))
+	}
+
+	renamed := validClaims()
+	renamed["name"] = "Renamed"
+	if _, err := v.CurrentUser(context.Background(), sign(t, jwtv5.SigningMethodEdDSA, private, renamed), testAppID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dir.saved) != 2 || dir.saved[1].Name != "Renamed" {
+		t.Errorf("changed profile was not saved, got %+v", dir.saved)
+	}
+}
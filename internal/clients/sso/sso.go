@@ -0,0 +1,25 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"simple-chat/internal/domain/models"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrUserExists         = errors.New("user already exists")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrInvalidArgument    = errors.New("invalid argument")
+	ErrUnavailable        = errors.New("authentication service is unavailable")
+)
+
+// Provider authenticates chat users. It is implemented by the SSO gRPC
+// client and by the static provider used to run the chat standalone.
+type Provider interface {
+	Register(ctx context.Context, email string, password string, name string) (userID int64, err error)
+	Login(ctx context.Context, email string, password string, appID int32) (models.Tokens, error)
+	RefreshToken(ctx context.Context, token string, appID int32) (models.Tokens, error)
+	CurrentUser(ctx context.Context, token string, appID int32) (models.User, error)
+	GetUsers(ctx context.Context, appID int32, userIDs []int64) (map[int64]models.User, error)
+}
@@ -0,0 +1,202 @@
+package static
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"simple-chat/internal/clients/sso"
+	"simple-chat/internal/config"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// Provider is an in-memory authentication provider for local development
+// and tests. Users and their fixed tokens are loaded from a YAML file;
+// registrations and tokens issued by Login live only as long as the process.
+// Passwords are compared in plain text, so it must not be used in production.
+type Provider struct {
+	log      *slog.Logger
+	tokenTTL time.Duration
+
+	mu            sync.RWMutex
+	nextID        int64
+	users         map[int64]staticUser
+	emails        map[string]int64
+	accessTokens  map[string]issuedToken
+	refreshTokens map[string]issuedToken
+}
+
+type usersFile struct {
+	Users []staticUser `yaml:"users"`
+}
+
+type staticUser struct {
+	ID       int64    `yaml:"id"`
+	Email    string   `yaml:"email"`
+	Name     string   `yaml:"name"`
+	Password string   `yaml:"password"`
+	Tokens   []string `yaml:"tokens"`
+}
+
+type issuedToken struct {
+	userID    int64
+	appID     int32
+	expiresAt time.Time
+}
+
+func New(log *slog.Logger, cfg config.Static) (*Provider, error) {
+	const op = "client.sso.static.New"
+
+	p := &Provider{
+		log:           log,
+		tokenTTL:      cfg.TokenTTL,
+		users:         make(map[int64]staticUser),
+		emails:        make(map[string]int64),
+		accessTokens:  make(map[string]issuedToken),
+		refreshTokens: make(map[string]issuedToken),
+	}
+
+	if cfg.UsersPath == "" {
+		return p, nil
+	}
+
+	var file usersFile
+	if err := cleanenv.ReadConfig(cfg.UsersPath, &file); err != nil {
+		log.Error("failed to read static users", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	for _, user := range file.Users {
+		user.Email = strings.ToLower(user.Email)
+		p.users[user.ID] = user
+		p.emails[user.Email] = user.ID
+		if user.ID > p.nextID {
+			p.nextID = user.ID
+		}
+		for _, token := range user.Tokens {
+			// Fixed tokens are valid for every app and never expire.
+			p.accessTokens[token] = issuedToken{userID: user.ID}
+		}
+	}
+	log.Info("static users loaded", slog.Int("count", len(file.Users)))
+
+	return p, nil
+}
+
+func (p *Provider) Register(ctx context.Context, email string, password string, name string) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	email = strings.ToLower(email)
+	if _, ok := p.emails[email]; ok {
+		return 0, sso.ErrUserExists
+	}
+
+	p.nextID++
+	user := staticUser{ID: p.nextID, Email: email, Name: name, Password: password}
+	p.users[user.ID] = user
+	p.emails[email] = user.ID
+
+	return user.ID, nil
+}
+
+func (p *Provider) Login(ctx context.Context, email string, password string, appID int32) (models.Tokens, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	userID, ok := p.emails[strings.ToLower(email)]
+	if !ok {
+		return models.Tokens{}, sso.ErrInvalidCredentials
+	}
+	user := p.users[userID]
+	if subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) != 1 {
+		return models.Tokens{}, sso.ErrInvalidCredentials
+	}
+
+	return p.issueTokens(userID, appID)
+}
+
+func (p *Provider) RefreshToken(ctx context.Context, token string, appID int32) (models.Tokens, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	issued, ok := p.refreshTokens[token]
+	if !ok || issued.appID != appID || time.Now().After(issued.expiresAt) {
+		return models.Tokens{}, sso.ErrInvalidToken
+	}
+	delete(p.refreshTokens, token)
+
+	return p.issueTokens(issued.userID, appID)
+}
+
+func (p *Provider) CurrentUser(ctx context.Context, token string, appID int32) (models.User, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	issued, ok := p.accessTokens[token]
+	if !ok {
+		return models.User{}, sso.ErrInvalidToken
+	}
+	if !issued.expiresAt.IsZero() && (issued.appID != appID || time.Now().After(issued.expiresAt)) {
+		return models.User{}, sso.ErrInvalidToken
+	}
+
+	return p.users[issued.userID].model(), nil
+}
+
+func (p *Provider) GetUsers(ctx context.Context, appID int32, userIDs []int64) (map[int64]models.User, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	users := make(map[int64]models.User, len(userIDs))
+	for _, userID := range userIDs {
+		if user, ok := p.users[userID]; ok {
+			users[userID] = user.model()
+		}
+	}
+
+	return users, nil
+}
+
+func (p *Provider) issueTokens(userID int64, appID int32) (models.Tokens, error) {
+	accessToken, err := randomToken()
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	refreshToken, err := randomToken()
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	now := time.Now()
+	p.accessTokens[accessToken] = issuedToken{userID: userID, appID: appID, expiresAt: now.Add(p.tokenTTL)}
+	p.refreshTokens[refreshToken] = issuedToken{userID: userID, appID: appID, expiresAt: now.Add(p.tokenTTL * 24)}
+
+	return models.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (u staticUser) model() models.User {
+	return models.User{
+		UserID: u.ID,
+		Email:  u.Email,
+		Name:   u.Name,
+	}
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
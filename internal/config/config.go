@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"os"
 	"time"
@@ -15,7 +16,18 @@ type Config struct {
 	AppID          int32  `yaml:"app_id" env-required:"true"`
 	Database       `yaml:"database" env-required:"true"`
 	HTTPServer     `yaml:"http_server" env-required:"true"`
+	Metrics        `yaml:"metrics"`
 	SSOClient      `yaml:"sso_client" env-required:"true"`
+	Auth           `yaml:"auth"`
+	Websocket      `yaml:"websocket"`
+	RateLimit      `yaml:"rate_limit"`
+	AuthThrottle   `yaml:"auth_throttle"`
+	MessageContent `yaml:"message_content"`
+	Moderation     `yaml:"moderation"`
+	Admin          `yaml:"admin"`
+	Avatars        `yaml:"avatars"`
+	Scheduler      `yaml:"scheduler"`
+	Sweeper        `yaml:"sweeper"`
 }
 
 type Database struct {
@@ -31,16 +43,192 @@ type Database struct {
 }
 
 type HTTPServer struct {
-	Host        string        `yaml:"host" env-required:"true"`
-	Port        int           `yaml:"port" env-required:"true"`
-	Timeout     time.Duration `yaml:"timeout" env-required:"true"`
-	IdleTimeout time.Duration `yaml:"idle_timeout" env-required:"true"`
+	Host            string        `yaml:"host" env-required:"true"`
+	Port            int           `yaml:"port" env-required:"true"`
+	Timeout         time.Duration `yaml:"timeout" env-required:"true"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env-required:"true"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"30s"`
+	// TrustedProxies are the addresses or CIDR ranges whose forwarding
+	// headers are believed when resolving the client IP.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// Metrics is the listener that serves /metrics. It is kept apart from the
+// public router and bound to localhost unless configured otherwise.
+type Metrics struct {
+	Host string `yaml:"host" env-default:"127.0.0.1"`
+	Port int    `yaml:"port" env-default:"9100"`
 }
 
 type SSOClient struct {
 	Address      string        `yaml:"address" env-required:"true"`
 	Timeout      time.Duration `yaml:"timeout" env-required:"true"`
 	RetriesCount int           `yaml:"retries_count" env-required:"true"`
+	ProfileCache `yaml:"profile_cache"`
+	TokenCache   `yaml:"token_cache"`
+}
+
+type ProfileCache struct {
+	TTL  time.Duration `yaml:"ttl" env-default:"1m"`
+	Size int           `yaml:"size" env-default:"10000"`
+}
+
+type TokenCache struct {
+	TTL         time.Duration `yaml:"ttl" env-default:"30s"`
+	NegativeTTL time.Duration `yaml:"negative_ttl" env-default:"5s"`
+	Size        int           `yaml:"size" env-default:"10000"`
+}
+
+const (
+	AuthProviderSSO    = "sso"
+	AuthProviderStatic = "static"
+
+	AuthStrategySSO = "sso"
+	AuthStrategyJWT = "jwt"
+)
+
+type Auth struct {
+	Provider string `yaml:"provider" env-default:"sso"`
+	Strategy string `yaml:"strategy" env-default:"sso"`
+	Static   `yaml:"static"`
+	JWT      `yaml:"jwt"`
+}
+
+type Static struct {
+	UsersPath string        `yaml:"users_path"`
+	TokenTTL  time.Duration `yaml:"token_ttl" env-default:"1h"`
+}
+
+type JWT struct {
+	PublicKeyPath string        `yaml:"public_key_path"`
+	JWKSPath      string        `yaml:"jwks_path"`
+	Leeway        time.Duration `yaml:"leeway" env-default:"5s"`
+	Fallback      bool          `yaml:"fallback" env-default:"false"`
+}
+
+type Websocket struct {
+	AllowedOrigins []string      `yaml:"allowed_origins"`
+	TicketTTL      time.Duration `yaml:"ticket_ttl" env-default:"30s"`
+	MaxTickets     int           `yaml:"max_tickets" env-default:"10000"`
+	ReauthInterval time.Duration `yaml:"reauth_interval" env-default:"1m"`
+	PingInterval   time.Duration `yaml:"ping_interval" env-default:"30s"`
+	PongTimeout    time.Duration `yaml:"pong_timeout" env-default:"60s"`
+	WriteTimeout   time.Duration `yaml:"write_timeout" env-default:"10s"`
+	MaxMessageSize int64         `yaml:"max_message_size" env-default:"4096"`
+	SendBuffer     int           `yaml:"send_buffer" env-default:"32"`
+}
+
+// validate rejects timings the connection loop cannot run with: a ticker
+// needs a positive interval and pings must arrive before the pong timeout.
+func (w Websocket) validate() error {
+	if w.PingInterval <= 0 || w.PongTimeout <= 0 || w.WriteTimeout <= 0 {
+		return errors.New("ping_interval, pong_timeout and write_timeout must be positive")
+	}
+	if w.PingInterval >= w.PongTimeout {
+		return errors.New("ping_interval must be shorter than pong_timeout")
+	}
+	return nil
+}
+
+// RateLimit holds the message send limits. Each rate has its own type so it
+// can carry defaults; leaving a rate out of the config does not lift it.
+type RateLimit struct {
+	Enabled       bool           `yaml:"enabled" env-default:"true"`
+	PerUser       UserRate       `yaml:"per_user"`
+	PerConnection ConnectionRate `yaml:"per_connection"`
+	PerChat       ChatRate       `yaml:"per_chat"`
+}
+
+type UserRate struct {
+	Events int           `yaml:"events" env-default:"20"`
+	Period time.Duration `yaml:"period" env-default:"10s"`
+	Burst  int           `yaml:"burst" env-default:"10"`
+}
+
+type ConnectionRate struct {
+	Events int           `yaml:"events" env-default:"10"`
+	Period time.Duration `yaml:"period" env-default:"10s"`
+	Burst  int           `yaml:"burst" env-default:"5"`
+}
+
+type ChatRate struct {
+	Events int           `yaml:"events" env-default:"100"`
+	Period time.Duration `yaml:"period" env-default:"10s"`
+	Burst  int           `yaml:"burst" env-default:"50"`
+}
+
+type AuthThrottle struct {
+	Enabled             bool          `yaml:"enabled" env-default:"true"`
+	MaxFailuresPerIP    int           `yaml:"max_failures_per_ip" env-default:"20"`
+	MaxFailuresPerEmail int           `yaml:"max_failures_per_email" env-default:"5"`
+	FailureWindow       time.Duration `yaml:"failure_window" env-default:"15m"`
+	BaseLockout         time.Duration `yaml:"base_lockout" env-default:"1m"`
+	MaxLockout          time.Duration `yaml:"max_lockout" env-default:"1h"`
+	ResetAfter          time.Duration `yaml:"reset_after" env-default:"24h"`
+	RegisterPerIP       RegisterRate  `yaml:"register_per_ip"`
+}
+
+// RegisterRate is the registration rate per client IP. It has defaults, so
+// leaving it out of the config does not lift the limit.
+type RegisterRate struct {
+	Events int           `yaml:"events" env-default:"5"`
+	Period time.Duration `yaml:"period" env-default:"1h"`
+	Burst  int           `yaml:"burst" env-default:"3"`
+}
+
+type MessageContent struct {
+	// MaxLength is the limit in characters; zero disables it.
+	MaxLength    int  `yaml:"max_length" env-default:"4096"`
+	Normalize    bool `yaml:"normalize" env-default:"true"`
+	StripControl bool `yaml:"strip_control" env-default:"true"`
+	EscapeHTML   bool `yaml:"escape_html" env-default:"false"`
+}
+
+type Moderation struct {
+	Enabled    bool `yaml:"enabled" env-default:"true"`
+	WordFilter `yaml:"word_filter"`
+	Spam       `yaml:"spam"`
+}
+
+type WordFilter struct {
+	// Action is one of mask, flag or reject.
+	Action string `yaml:"action" env-default:"mask"`
+	// Words maps a locale such as "en" to its word list.
+	Words map[string][]string `yaml:"words"`
+}
+
+type Spam struct {
+	Action     string        `yaml:"action" env-default:"reject"`
+	Window     time.Duration `yaml:"window" env-default:"1m"`
+	MaxRepeats int           `yaml:"max_repeats" env-default:"3"`
+	MaxCharRun int           `yaml:"max_char_run" env-default:"30"`
+}
+
+type Admin struct {
+	// UserIDs are always treated as admins.
+	UserIDs []int64 `yaml:"user_ids"`
+	// SSOCheck also asks SSO, when it is the auth provider, whether a user is
+	// an admin.
+	SSOCheck bool          `yaml:"sso_check" env-default:"true"`
+	CacheTTL time.Duration `yaml:"cache_ttl" env-default:"1m"`
+}
+
+type Avatars struct {
+	Dir string `yaml:"dir" env-default:"./storage/avatars"`
+	// MaxSize is the largest accepted upload in bytes.
+	MaxSize int64 `yaml:"max_size" env-default:"2097152"`
+}
+
+type Scheduler struct {
+	// Interval is how often due scheduled messages are looked for.
+	Interval  time.Duration `yaml:"interval" env-default:"5s"`
+	BatchSize int           `yaml:"batch_size" env-default:"100"`
+}
+
+type Sweeper struct {
+	// Interval is how often expired messages are purged.
+	Interval  time.Duration `yaml:"interval" env-default:"1m"`
+	BatchSize int           `yaml:"batch_size" env-default:"500"`
 }
 
 func MustLoad() *Config {
@@ -67,6 +255,9 @@ func MustLoadWithPath(configPath string) *Config {
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
 		log.Fatal("failed to read config, error: ", err)
 	}
+	if err := cfg.Websocket.validate(); err != nil {
+		log.Fatal("invalid websocket config, error: ", err)
+	}
 
 	return &cfg
 }
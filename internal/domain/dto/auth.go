@@ -17,8 +17,8 @@ func (r *RegisterRequest) Validate() error {
 	r.Password = strings.TrimSpace(r.Password)
 	r.Name = strings.TrimSpace(r.Name)
 
-	if err := validator.Validate(r); err != "" {
-		return fmt.Errorf("validation error: %s", err)
+	if err := validator.Validate(r); err != nil {
+		return fmt.Errorf("validation error: %w", err)
 	}
 	return nil
 }
@@ -32,8 +32,8 @@ func (r *LoginRequest) Validate() error {
 	r.Email = strings.TrimSpace(r.Email)
 	r.Password = strings.TrimSpace(r.Password)
 
-	if err := validator.Validate(r); err != "" {
-		return fmt.Errorf("validation error: %s", err)
+	if err := validator.Validate(r); err != nil {
+		return fmt.Errorf("validation error: %w", err)
 	}
 	return nil
 }
@@ -3,19 +3,25 @@ package dto
 import (
 	"fmt"
 	"simple-chat/internal/validator"
+	"strings"
 	"time"
 )
 
+// Chat is a new chat. Direct chats name their two users; group chats and
+// channels have members instead.
 type Chat struct {
-	FirstUserID  int64     `json:"first_user_id" validate:"required"`
-	SecondUserID int64     `json:"second_user_id" validate:"required"`
+	Type         string    `json:"type"`
+	FirstUserID  int64     `json:"first_user_id" validate:"required_without=Type"`
+	SecondUserID int64     `json:"second_user_id" validate:"required_without=Type"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
 	LastMessage  string    `json:"last_message"`
 	UpdatedAt    time.Time `json:"updated_at" validate:"required"`
 }
 
 func (c *Chat) Validate() error {
-	if err := validator.Validate(c); err != "" {
-		return fmt.Errorf("validation error: %s", err)
+	if err := validator.Validate(c); err != nil {
+		return fmt.Errorf("validation error: %w", err)
 	}
 	return nil
 }
@@ -26,8 +32,134 @@ type CreateChatRequest struct {
 }
 
 func (c *CreateChatRequest) Validate() error {
-	if err := validator.Validate(c); err != "" {
-		return fmt.Errorf("validation error: %s", err)
+	if err := validator.Validate(c); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+type CreateGroupRequest struct {
+	Title string `json:"title" validate:"required,max=128"`
+	// Members are added next to the creator, who becomes the owner.
+	Members []int64 `json:"members" validate:"max=200,dive,required"`
+}
+
+func (c *CreateGroupRequest) Validate() error {
+	c.Title = strings.TrimSpace(c.Title)
+
+	if err := validator.Validate(c); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+type CreateChannelRequest struct {
+	Title       string `json:"title" validate:"required,max=128"`
+	Description string `json:"description" validate:"max=1024"`
+}
+
+func (c *CreateChannelRequest) Validate() error {
+	c.Title = strings.TrimSpace(c.Title)
+	c.Description = strings.TrimSpace(c.Description)
+
+	if err := validator.Validate(c); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+type AddMembersRequest struct {
+	UserIDs []int64 `json:"user_ids" validate:"required,min=1,max=200,dive,required"`
+}
+
+func (a *AddMembersRequest) Validate() error {
+	if err := validator.Validate(a); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+type MemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin member"`
+}
+
+func (m *MemberRoleRequest) Validate() error {
+	if err := validator.Validate(m); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+type TransferOwnershipRequest struct {
+	UserID int64 `json:"user_id" validate:"required"`
+}
+
+func (t *TransferOwnershipRequest) Validate() error {
+	if err := validator.Validate(t); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+// ChatFilter narrows a user's chat list.
+type ChatFilter struct {
+	// HideBlocked leaves out chats with users the owner of the list blocked.
+	HideBlocked bool
+	// Archived keeps only archived or only unarchived chats when set.
+	Archived *bool
+}
+
+// ChatSettingsRequest replaces the user's settings for a chat; omitted
+// fields are reset.
+type ChatSettingsRequest struct {
+	MutedUntil  *time.Time `json:"muted_until"`
+	Archived    bool       `json:"archived"`
+	PinnedOrder *int       `json:"pinned_order" validate:"omitempty,min=0"`
+}
+
+func (c *ChatSettingsRequest) Validate() error {
+	if err := validator.Validate(c); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+// ChatInfoRequest changes a chat's title, description and avatar. Omitted
+// fields are left as they are and empty strings clear them.
+type ChatInfoRequest struct {
+	Title       *string `json:"title" validate:"omitempty,max=128"`
+	Description *string `json:"description" validate:"omitempty,max=1024"`
+	AvatarURL   *string `json:"avatar_url"`
+}
+
+func (c *ChatInfoRequest) Validate() error {
+	if c.Title != nil {
+		*c.Title = strings.TrimSpace(*c.Title)
+	}
+	if c.Description != nil {
+		*c.Description = strings.TrimSpace(*c.Description)
+	}
+
+	if err := validator.Validate(c); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	if c.AvatarURL != nil && *c.AvatarURL != "" {
+		if err := validator.Var("avatar_url", *c.AvatarURL, "max=2048,http_url"); err != nil {
+			return fmt.Errorf("validation error: %w", err)
+		}
+	}
+	return nil
+}
+
+// MessageTTLRequest sets how many seconds new messages stay in a chat, at
+// most a year. Zero turns disappearing messages off.
+type MessageTTLRequest struct {
+	TTL int `json:"ttl" validate:"min=0,max=31536000"`
+}
+
+func (m *MessageTTLRequest) Validate() error {
+	if err := validator.Validate(m); err != nil {
+		return fmt.Errorf("validation error: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"fmt"
+	"simple-chat/internal/validator"
+	"time"
+)
+
+// CreateInviteRequest creates an invite link that never expires and has no
+// use limit unless the fields are set.
+type CreateInviteRequest struct {
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty,gt"`
+	MaxUses   *int       `json:"max_uses" validate:"omitempty,min=1"`
+}
+
+func (c *CreateInviteRequest) Validate() error {
+	if err := validator.Validate(c); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
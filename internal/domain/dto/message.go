@@ -2,6 +2,7 @@ package dto
 
 import (
 	"fmt"
+	"simple-chat/internal/domain/models"
 	"simple-chat/internal/validator"
 	"strings"
 	"time"
@@ -12,13 +13,20 @@ type Message struct {
 	Sender    int64     `json:"sender" validate:"required"`
 	Text      string    `json:"text" validate:"required"`
 	CreatedAt time.Time `json:"created_at"`
+	// FlagReason marks the message for moderator review.
+	FlagReason string `json:"flag_reason,omitempty"`
+	// Type defaults to a text message.
+	Type  string              `json:"type,omitempty"`
+	Event *models.SystemEvent `json:"event,omitempty" validate:"-"`
+	// ExpiresAt is set from the chat's message TTL when the message is sent.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 func (m *Message) Validate() error {
 	m.Text = strings.TrimSpace(m.Text)
 
-	if err := validator.Validate(m); err != "" {
-		return fmt.Errorf("validation error: %s", err)
+	if err := validator.Validate(m); err != nil {
+		return fmt.Errorf("validation error: %w", err)
 	}
 	return nil
 }
@@ -31,8 +39,21 @@ type MessageRequest struct {
 func (r *MessageRequest) Validate() error {
 	r.Text = strings.TrimSpace(r.Text)
 
-	if err := validator.Validate(r); err != "" {
-		return fmt.Errorf("validation error: %s", err)
+	if err := validator.Validate(r); err != nil {
+		return fmt.Errorf("validation error: %w", err)
 	}
 	return nil
 }
+
+const (
+	FrameMessage = "message"
+	FrameReauth  = "reauth"
+)
+
+// WebsocketFrame is a frame sent by a client over the chat websocket. Frames
+// without a type are treated as messages.
+type WebsocketFrame struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Token string `json:"token"`
+}
@@ -0,0 +1,73 @@
+package dto
+
+import (
+	"fmt"
+	"simple-chat/internal/validator"
+	"strings"
+	"time"
+)
+
+// Report is a new report. Reporter is zero for automatic flags; MessageID and
+// Message are set when a message rather than a whole chat is reported, and
+// Message keeps a copy of it for reviewers.
+type Report struct {
+	Reporter  int64     `json:"reporter"`
+	ChatID    int64     `json:"chat_id" validate:"required"`
+	MessageID int64     `json:"message_id"`
+	Message   *Message  `json:"message" validate:"-"`
+	Reason    string    `json:"reason" validate:"required"`
+	CreatedAt time.Time `json:"created_at" validate:"required"`
+}
+
+func (r *Report) Validate() error {
+	if err := validator.Validate(r); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+// ReportRequest reports either a single message or a whole chat.
+type ReportRequest struct {
+	MessageID int64  `json:"message_id" validate:"required_without=ChatID"`
+	ChatID    int64  `json:"chat_id" validate:"required_without=MessageID"`
+	Reason    string `json:"reason" validate:"required,max=1000"`
+}
+
+func (r *ReportRequest) Validate() error {
+	r.Reason = strings.TrimSpace(r.Reason)
+
+	if err := validator.Validate(r); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+type ResolveReportRequest struct {
+	Status     string `json:"status" validate:"required,oneof=resolved dismissed"`
+	Resolution string `json:"resolution" validate:"max=1000"`
+}
+
+func (r *ResolveReportRequest) Validate() error {
+	r.Resolution = strings.TrimSpace(r.Resolution)
+
+	if err := validator.Validate(r); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+// SuspendRequest suspends a user until the given time, or indefinitely when
+// it is omitted.
+type SuspendRequest struct {
+	Reason string     `json:"reason" validate:"required,max=1000"`
+	Until  *time.Time `json:"until"`
+}
+
+func (r *SuspendRequest) Validate() error {
+	r.Reason = strings.TrimSpace(r.Reason)
+
+	if err := validator.Validate(r); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
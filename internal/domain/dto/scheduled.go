@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"fmt"
+	"simple-chat/internal/validator"
+	"strings"
+	"time"
+)
+
+type ScheduleMessageRequest struct {
+	ChatID int64     `json:"chat_id" validate:"required"`
+	Text   string    `json:"text" validate:"required"`
+	SendAt time.Time `json:"send_at" validate:"required,gt"`
+}
+
+func (r *ScheduleMessageRequest) Validate() error {
+	r.Text = strings.TrimSpace(r.Text)
+
+	if err := validator.Validate(r); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+// UpdateScheduledRequest changes a scheduled message's text and time.
+// Omitted fields are left as they are.
+type UpdateScheduledRequest struct {
+	Text   *string    `json:"text" validate:"omitempty,min=1"`
+	SendAt *time.Time `json:"send_at" validate:"omitempty,gt"`
+}
+
+func (r *UpdateScheduledRequest) Validate() error {
+	if r.Text != nil {
+		*r.Text = strings.TrimSpace(*r.Text)
+	}
+
+	if err := validator.Validate(r); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
@@ -2,10 +2,69 @@ package models
 
 import "time"
 
+const (
+	ChatDirect = "direct"
+	ChatGroup  = "group"
+	// ChatChannel is a broadcast chat: only the owner and admins post, the
+	// other members are read-only subscribers.
+	ChatChannel = "channel"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
 type Chat struct {
 	ID           int64     `json:"id"`
+	Type         string    `json:"type"`
 	FirstUserID  int64     `json:"first_user_id"`
 	SecondUserID int64     `json:"second_user_id"`
+	Title        string    `json:"title,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	AvatarURL    string    `json:"avatar_url,omitempty"`
+	MessageTTL   int       `json:"message_ttl,omitempty"`
 	LastMessage  string    `json:"last_message"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	Participants []User    `json:"participants,omitempty"`
+	// Members are listed for group chats only.
+	Members []ChatMember `json:"members,omitempty"`
+	// SubscriberCount is set for channels, which do not list their members.
+	SubscriberCount *int64 `json:"subscriber_count,omitempty"`
+	// Settings are the requesting user's settings for the chat.
+	Settings *ChatSettings `json:"settings,omitempty"`
+}
+
+// ChatSettings are a user's own settings for a chat.
+type ChatSettings struct {
+	MutedUntil *time.Time `json:"muted_until"`
+	Archived   bool       `json:"archived"`
+	// PinnedOrder places the chat at the top of the list, lowest first. Nil
+	// means the chat is not pinned.
+	PinnedOrder *int `json:"pinned_order"`
+}
+
+func (s ChatSettings) Muted(at time.Time) bool {
+	return s.MutedUntil != nil && s.MutedUntil.After(at)
+}
+
+// Other returns the other participant of a 1:1 chat.
+func (c Chat) Other(userID int64) int64 {
+	if c.FirstUserID == userID {
+		return c.SecondUserID
+	}
+	return c.FirstUserID
+}
+
+type ChatMember struct {
+	UserID   int64     `json:"user_id"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// CanManage reports whether the member may manage the chat's members and
+// info.
+func (m ChatMember) CanManage() bool {
+	return m.Role == RoleOwner || m.Role == RoleAdmin
 }
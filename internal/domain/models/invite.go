@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Invite is a shareable link that lets users join a group chat.
+type Invite struct {
+	ID        int64      `json:"id"`
+	ChatID    int64      `json:"chat_id"`
+	Token     string     `json:"token"`
+	CreatedBy int64      `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxUses   *int       `json:"max_uses,omitempty"`
+	Uses      int        `json:"uses"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
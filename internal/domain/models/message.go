@@ -2,10 +2,43 @@ package models
 
 import "time"
 
+const (
+	MessageText   = "text"
+	MessageSystem = "system"
+)
+
+// System events describe what a system message records.
+const (
+	EventChatCreated          = "chat_created"
+	EventMemberAdded          = "member_added"
+	EventMemberJoined         = "member_joined"
+	EventMemberRemoved        = "member_removed"
+	EventMemberLeft           = "member_left"
+	EventMemberPromoted       = "member_promoted"
+	EventMemberDemoted        = "member_demoted"
+	EventOwnershipTransferred = "ownership_transferred"
+	EventTTLChanged           = "ttl_changed"
+)
+
 type Message struct {
-	ID        int64     `json:"id"`
-	ChatID    int64     `json:"chat_id"`
-	Sender    int64     `json:"sender"`
-	Text      string    `json:"text"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            int64        `json:"id"`
+	ChatID        int64        `json:"chat_id"`
+	Type          string       `json:"type"`
+	Sender        int64        `json:"sender"`
+	Text          string       `json:"text"`
+	CreatedAt     time.Time    `json:"created_at"`
+	ExpiresAt     *time.Time   `json:"expires_at,omitempty"`
+	Event         *SystemEvent `json:"event,omitempty"`
+	SenderProfile *User        `json:"sender_profile,omitempty"`
+}
+
+// SystemEvent is the machine-readable form of a system message, so clients
+// can render it in their own language. Actor is also the message sender.
+type SystemEvent struct {
+	Action string `json:"action"`
+	Actor  int64  `json:"actor"`
+	Target int64  `json:"target,omitempty"`
+	// TTL is the new message TTL in seconds for ttl_changed events; it is
+	// omitted when disappearing messages were turned off.
+	TTL int `json:"ttl,omitempty"`
 }
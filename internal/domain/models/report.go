@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	ReportOpen      = "open"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+type Report struct {
+	ID int64 `json:"id"`
+	// Reporter is empty for messages flagged by automatic moderation.
+	Reporter   int64      `json:"reporter,omitempty"`
+	ChatID     int64      `json:"chat_id"`
+	MessageID  int64      `json:"message_id,omitempty"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	Resolution string     `json:"resolution,omitempty"`
+	ResolvedBy int64      `json:"resolved_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	// Message is the reported message as it was when reported.
+	Message *Message `json:"message,omitempty"`
+}
+
+type Suspension struct {
+	UserID      int64      `json:"user_id"`
+	Reason      string     `json:"reason"`
+	SuspendedBy int64      `json:"suspended_by"`
+	Until       *time.Time `json:"until,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	ScheduledPending = "pending"
+	// ScheduledFailed messages could not be delivered, such as when the
+	// sender left the chat in the meantime. Error says why.
+	ScheduledFailed = "failed"
+)
+
+// ScheduledMessage is a message that is sent to its chat at SendAt.
+type ScheduledMessage struct {
+	ID        int64     `json:"id"`
+	ChatID    int64     `json:"chat_id"`
+	Sender    int64     `json:"sender"`
+	Text      string    `json:"text"`
+	SendAt    time.Time `json:"send_at"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
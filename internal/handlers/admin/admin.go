@@ -0,0 +1,262 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/logger/sl"
+	authMiddleware "simple-chat/internal/lib/middleware"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type AdminHandler struct {
+	log           *slog.Logger
+	reportService ReportService
+	userService   UserService
+	broadcaster   Broadcaster
+}
+
+type ReportService interface {
+	GetOpenReports(ctx context.Context, limit int, offset int) ([]models.Report, error)
+	ResolveReport(ctx context.Context, reportID int64, adminID int64, req dto.ResolveReportRequest) error
+	DeleteMessage(ctx context.Context, messageID int64, adminID int64) (int64, error)
+}
+
+// Broadcaster tells connected clients about deleted messages.
+type Broadcaster interface {
+	BroadcastDeleted(chatID int64, messageIDs []int64)
+}
+
+type UserService interface {
+	SuspendUser(ctx context.Context, suspension models.Suspension) error
+	LiftSuspension(ctx context.Context, userID int64) error
+}
+
+func NewAdminHandler(log *slog.Logger, reportService ReportService, userService UserService, broadcaster Broadcaster) *AdminHandler {
+	return &AdminHandler{
+		log:           log,
+		reportService: reportService,
+		userService:   userService,
+		broadcaster:   broadcaster,
+	}
+}
+
+func AddAdminHandler(
+	log *slog.Logger,
+	reportService ReportService,
+	userService UserService,
+	broadcaster Broadcaster,
+	verifier authMiddleware.TokenVerifier,
+	admins authMiddleware.AdminChecker,
+	appID int32,
+) func(r chi.Router) {
+	adminHandler := NewAdminHandler(log, reportService, userService, broadcaster)
+
+	return func(r chi.Router) {
+		r.Use(authMiddleware.Auth(log, verifier, appID))
+		r.Use(authMiddleware.RequireAdmin(log, admins))
+
+		r.Get("/reports", adminHandler.GetOpenReports(context.Background()))
+		r.Post("/reports/{report_id}/resolve", adminHandler.ResolveReport(context.Background()))
+		r.Delete("/messages/{message_id}", adminHandler.DeleteMessage(context.Background()))
+		r.Post("/users/{user_id}/suspension", adminHandler.SuspendUser(context.Background()))
+		r.Delete("/users/{user_id}/suspension", adminHandler.LiftSuspension(context.Background()))
+	}
+}
+
+func (h *AdminHandler) GetOpenReports(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.admin.GetOpenReports"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit <= 0 {
+			limit = 10
+		}
+		offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+		if err != nil || offset < 0 {
+			offset = 0
+		}
+
+		reports, err := h.reportService.GetOpenReports(ctx, limit, offset)
+		if err != nil {
+			h.log.Error("failed to get open reports", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to get reports")
+			return
+		}
+		if reports == nil {
+			reports = []models.Report{}
+		}
+
+		handlers.SuccessResponse(w, r, 200, reports)
+	}
+}
+
+func (h *AdminHandler) ResolveReport(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.admin.ResolveReport"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		reportID, err := strconv.ParseInt(chi.URLParam(r, "report_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse report_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		var req dto.ResolveReportRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		admin := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if err := h.reportService.ResolveReport(ctx, reportID, admin.UserID, req); err != nil {
+			h.log.Error("failed to resolve report", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to resolve report")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message":   "report successfully resolved",
+			"report_id": reportID,
+		})
+	}
+}
+
+func (h *AdminHandler) DeleteMessage(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.admin.DeleteMessage"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		messageID, err := strconv.ParseInt(chi.URLParam(r, "message_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse message_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		admin := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		chatID, err := h.reportService.DeleteMessage(ctx, messageID, admin.UserID)
+		if err != nil {
+			h.log.Error("failed to delete message", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to delete message")
+			return
+		}
+		h.broadcaster.BroadcastDeleted(chatID, []int64{messageID})
+		h.log.Info("message deleted by admin", slog.Int64("message_id", messageID), slog.Int64("admin_id", admin.UserID))
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message":    "message successfully deleted",
+			"message_id": messageID,
+		})
+	}
+}
+
+func (h *AdminHandler) SuspendUser(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.admin.SuspendUser"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse user_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		var req dto.SuspendRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		now := time.Now().UTC()
+		if req.Until != nil && !req.Until.After(now) {
+			handlers.CodedErrorResponse(w, r, 422, handlers.CodeValidationFailed, "until must be in the future")
+			return
+		}
+
+		admin := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		suspension := models.Suspension{
+			UserID:      userID,
+			Reason:      req.Reason,
+			SuspendedBy: admin.UserID,
+			Until:       req.Until,
+			CreatedAt:   now,
+		}
+		if err := h.userService.SuspendUser(ctx, suspension); err != nil {
+			h.log.Error("failed to suspend user", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to suspend user")
+			return
+		}
+		h.log.Info("user suspended", slog.Int64("user_id", userID), slog.Int64("admin_id", admin.UserID))
+
+		handlers.SuccessResponse(w, r, 200, suspension)
+	}
+}
+
+func (h *AdminHandler) LiftSuspension(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.admin.LiftSuspension"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse user_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		if err := h.userService.LiftSuspension(ctx, userID); err != nil {
+			h.log.Error("failed to lift suspension", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to lift suspension")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message": "suspension successfully lifted",
+			"user_id": userID,
+		})
+	}
+}
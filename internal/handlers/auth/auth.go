@@ -2,44 +2,57 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	ssogrpc "simple-chat/internal/clients/sso/grpc"
+	"simple-chat/internal/clients/sso"
 	"simple-chat/internal/domain/dto"
 	"simple-chat/internal/domain/models"
 	"simple-chat/internal/handlers"
 	"simple-chat/internal/lib/logger/sl"
 	authMiddleware "simple-chat/internal/lib/middleware"
+	"simple-chat/internal/lib/ratelimit"
 	"strings"
+	"time"
 
-	ssov1 "github.com/bordviz/sso-protos/gen/go/sso"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 )
 
 type AuthHandler struct {
-	GRPCClient *ssogrpc.Client
-	log        *slog.Logger
-	AppID      int32
+	Provider sso.Provider
+	Verifier authMiddleware.TokenVerifier
+	Throttle Throttle
+	log      *slog.Logger
+	AppID    int32
 }
 
-func NewAuthHandler(gRPCClient *ssogrpc.Client, log *slog.Logger, appID int32) *AuthHandler {
+type Throttle interface {
+	LoginLocked(ip string, email string) time.Duration
+	LoginFailed(ip string, email string) (lockedFor time.Duration, key string)
+	LoginSucceeded(email string)
+	AllowRegister(ctx context.Context, ip string) (ratelimit.Result, error)
+}
+
+func NewAuthHandler(provider sso.Provider, verifier authMiddleware.TokenVerifier, throttle Throttle, log *slog.Logger, appID int32) *AuthHandler {
 	return &AuthHandler{
-		GRPCClient: gRPCClient,
-		log:        log,
-		AppID:      appID,
+		Provider: provider,
+		Verifier: verifier,
+		Throttle: throttle,
+		log:      log,
+		AppID:    appID,
 	}
 }
 
-func AddAuthHandler(gRPCClient *ssogrpc.Client, log *slog.Logger, appID int32) func(r chi.Router) {
-	authHandler := NewAuthHandler(gRPCClient, log, appID)
+func AddAuthHandler(provider sso.Provider, verifier authMiddleware.TokenVerifier, throttle Throttle, log *slog.Logger, appID int32) func(r chi.Router) {
+	authHandler := NewAuthHandler(provider, verifier, throttle, log, appID)
 
 	return func(r chi.Router) {
 		r.Post("/register", authHandler.Register(context.Background()))
 		r.Post("/login", authHandler.Login(context.Background()))
-		r.With(authMiddleware.Auth(log, authHandler.GRPCClient, authHandler.AppID)).
+		r.With(authMiddleware.Auth(log, authHandler.Verifier, authHandler.AppID)).
 			Get("/current_user", authHandler.CurrentUser(context.Background()))
 		r.Get("/refresh_token", authHandler.RefreshToken(context.Background()))
 	}
@@ -64,28 +77,31 @@ func (h *AuthHandler) Register(ctx context.Context) http.HandlerFunc {
 
 		if err := req.Validate(); err != nil {
 			h.log.Error("failed to validate request", sl.Err(err))
-			handlers.ErrorResponse(w, r, 422, err.Error())
+			handlers.ValidationErrorResponse(w, r, err)
 			return
 		}
 
-		userID, err := h.GRPCClient.Api.Register(
-			ctx,
-			&ssov1.RegisterRequest{
-				Email:    req.Email,
-				Password: req.Password,
-				Name:     req.Name,
-			},
-		)
+		ip := handlers.ClientIP(r)
+		limit, err := h.Throttle.AllowRegister(ctx, ip)
+		if err != nil {
+			h.log.Error("failed to check register rate limit", sl.Err(err))
+		} else if !limit.Allowed {
+			h.log.Warn("register rate limit exceeded", slog.String("ip", ip))
+			handlers.TooManyRequestsResponse(w, r, limit.RetryAfter, "too many registrations")
+			return
+		}
+
+		userID, err := h.Provider.Register(ctx, req.Email, req.Password, req.Name)
 
 		if err != nil {
 			h.log.Error("failed to register user", sl.Err(err))
-			handlers.ErrorResponse(w, r, 500, "failed to register user")
+			providerErrorResponse(w, r, err, "failed to register user")
 			return
 		}
 
 		handlers.SuccessResponse(w, r, 201, map[string]any{
 			"message": "user registered successfully",
-			"user_id": userID.UserId,
+			"user_id": userID,
 		})
 	}
 }
@@ -109,24 +125,34 @@ func (h *AuthHandler) Login(ctx context.Context) http.HandlerFunc {
 
 		if err := req.Validate(); err != nil {
 			h.log.Error("failed to validate request", sl.Err(err))
-			handlers.ErrorResponse(w, r, 422, err.Error())
+			handlers.ValidationErrorResponse(w, r, err)
 			return
 		}
 
-		tokensPair, err := h.GRPCClient.Api.Login(
-			ctx,
-			&ssov1.LoginRequest{
-				Email:    req.Email,
-				Password: req.Password,
-				AppId:    h.AppID,
-			},
-		)
+		ip := handlers.ClientIP(r)
+		if locked := h.Throttle.LoginLocked(ip, req.Email); locked > 0 {
+			h.log.Warn("login attempt while locked out", slog.String("ip", ip), slog.String("email", req.Email))
+			handlers.TooManyRequestsResponse(w, r, locked, "too many failed login attempts")
+			return
+		}
+
+		tokensPair, err := h.Provider.Login(ctx, req.Email, req.Password, h.AppID)
 
 		if err != nil {
+			if errors.Is(err, sso.ErrInvalidCredentials) {
+				if locked, key := h.Throttle.LoginFailed(ip, req.Email); locked > 0 {
+					h.log.Warn("login locked out",
+						slog.String("key", key),
+						slog.String("ip", ip),
+						slog.String("lockout", locked.String()),
+					)
+				}
+			}
 			h.log.Error("failed to login user", sl.Err(err))
-			handlers.ErrorResponse(w, r, 500, "failed to login user")
+			providerErrorResponse(w, r, err, "failed to login user")
 			return
 		}
+		h.Throttle.LoginSucceeded(req.Email)
 
 		handlers.SuccessResponse(w, r, 200, map[string]string{
 			"access_token":  tokensPair.AccessToken,
@@ -179,16 +205,14 @@ func (h *AuthHandler) RefreshToken(ctx context.Context) http.HandlerFunc {
 
 		token := strings.ReplaceAll(r.Header.Get("Authorization"), "Bearer ", "")
 
-		tokensPair, err := h.GRPCClient.Api.RefreshToken(
-			ctx,
-			&ssov1.RefreshTokenRequest{
-				Token: token,
-				AppId: h.AppID,
-			},
-		)
+		tokensPair, err := h.Provider.RefreshToken(ctx, token, h.AppID)
 		if err != nil {
-			h.log.Error("unauthorized", sl.Err(err))
-			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			h.log.Error("failed to refresh token", sl.Err(err))
+			if errors.Is(err, sso.ErrUnavailable) {
+				providerErrorResponse(w, r, err, "failed to refresh token")
+				return
+			}
+			handlers.CodedErrorResponse(w, r, 401, handlers.CodeInvalidToken, "unauthorized")
 			return
 		}
 
@@ -198,3 +222,23 @@ func (h *AuthHandler) RefreshToken(ctx context.Context) http.HandlerFunc {
 		})
 	}
 }
+
+// providerErrorResponse maps an error from the auth provider to an HTTP status
+// and error code. Unrecognised errors are reported as internal with fallback
+// as the detail.
+func providerErrorResponse(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	switch {
+	case errors.Is(err, sso.ErrInvalidCredentials):
+		handlers.CodedErrorResponse(w, r, 401, handlers.CodeInvalidCredentials, "invalid email or password")
+	case errors.Is(err, sso.ErrInvalidToken):
+		handlers.CodedErrorResponse(w, r, 401, handlers.CodeInvalidToken, "invalid token")
+	case errors.Is(err, sso.ErrUserExists):
+		handlers.CodedErrorResponse(w, r, 409, handlers.CodeUserExists, "user already exists")
+	case errors.Is(err, sso.ErrInvalidArgument):
+		handlers.CodedErrorResponse(w, r, 422, handlers.CodeValidationFailed, "invalid registration data")
+	case errors.Is(err, sso.ErrUnavailable):
+		handlers.CodedErrorResponse(w, r, 503, handlers.CodeAuthUnavailable, "authentication service is unavailable")
+	default:
+		handlers.CodedErrorResponse(w, r, 500, handlers.CodeInternal, fallback)
+	}
+}
@@ -0,0 +1,127 @@
+package chat
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/logger/sl"
+	authMiddleware "simple-chat/internal/lib/middleware"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+func (h *ChatHandler) CreateChannel(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.CreateChannel"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req dto.CreateChannelRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		chat, err := h.chatService.CreateChannel(ctx, user.UserID, req)
+		if err != nil {
+			h.log.Error("failed to create channel", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to create channel")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 201, chat)
+	}
+}
+
+func (h *ChatHandler) Subscribe(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.Subscribe"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		chat, err := h.chatService.Subscribe(ctx, user.UserID, chatID)
+		if err != nil {
+			h.log.Error("failed to subscribe to channel", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to subscribe to channel")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 200, chat)
+	}
+}
+
+func (h *ChatHandler) Unsubscribe(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.Unsubscribe"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		if err := h.chatService.Unsubscribe(ctx, user.UserID, chatID); err != nil {
+			h.log.Error("failed to unsubscribe from channel", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to unsubscribe from channel")
+			return
+		}
+		h.hub.Kick(chatID, user.UserID)
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message": "channel successfully unsubscribed",
+			"chat_id": chatID,
+		})
+	}
+}
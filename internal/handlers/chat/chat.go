@@ -2,62 +2,152 @@ package chat
 
 import (
 	"context"
-	"errors"
 	"log/slog"
 	"net/http"
-	ssogrpc "simple-chat/internal/clients/sso/grpc"
+	"simple-chat/internal/config"
 	"simple-chat/internal/domain/dto"
 	"simple-chat/internal/domain/models"
 	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/avatar"
 	"simple-chat/internal/lib/logger/sl"
 	authMiddleware "simple-chat/internal/lib/middleware"
+	"simple-chat/internal/lib/moderation"
+	"simple-chat/internal/lib/ratelimit"
+	"simple-chat/internal/lib/ticket"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
-	"github.com/gorilla/websocket"
 )
 
 type ChatHandler struct {
 	log            *slog.Logger
 	chatService    ChatService
 	messageService MessageService
+	users          UserProvider
+	limiter        MessageLimiter
+	verifier       authMiddleware.TokenVerifier
+	tickets        *ticket.Store
+	hub            *Hub
+	avatars        *avatar.Store
+	wsCfg          config.Websocket
 	appID          int32
 }
 
 type ChatService interface {
-	CreateChat(ctx context.Context, chat *dto.Chat) (chatID int64, err error)
+	CreateChat(ctx context.Context, creator int64, chat *dto.Chat) (chatID int64, err error)
 	GetChatByID(ctx context.Context, chatID int64) (models.Chat, error)
-	GetUserChats(ctx context.Context, userID int64, limit int, offset int) ([]models.Chat, error)
-	UpdateChatMessage(ctx context.Context, chatID int64, message string, updatedAt time.Time) (err error)
+	GetUserChats(ctx context.Context, userID int64, filter dto.ChatFilter, limit int, offset int) ([]models.Chat, error)
+	GetChatSettings(ctx context.Context, userID int64, chatID int64) (models.ChatSettings, error)
+	UpdateChatSettings(ctx context.Context, userID int64, chatID int64, req dto.ChatSettingsRequest) (models.ChatSettings, error)
+	UpdateChatInfo(ctx context.Context, userID int64, chatID int64, info dto.ChatInfoRequest) (models.Chat, error)
+	SetMessageTTL(ctx context.Context, userID int64, chatID int64, ttl int) (models.Message, error)
+	CheckMember(ctx context.Context, userID int64, chatID int64) error
+	CreateGroup(ctx context.Context, creator int64, req dto.CreateGroupRequest) (models.Chat, error)
+	AddMembers(ctx context.Context, actor int64, chatID int64, userIDs []int64) ([]models.Message, error)
+	RemoveMember(ctx context.Context, actor int64, chatID int64, userID int64) (models.Message, error)
+	LeaveChat(ctx context.Context, userID int64, chatID int64) (models.Message, error)
+	SetMemberRole(ctx context.Context, actor int64, chatID int64, userID int64, role string) (models.Message, error)
+	TransferOwnership(ctx context.Context, actor int64, chatID int64, userID int64) (models.Message, error)
+	CreateInvite(ctx context.Context, actor int64, chatID int64, req dto.CreateInviteRequest) (models.Invite, error)
+	GetInvites(ctx context.Context, actor int64, chatID int64) ([]models.Invite, error)
+	RevokeInvite(ctx context.Context, actor int64, chatID int64, inviteID int64) error
+	JoinByInvite(ctx context.Context, userID int64, token string) (models.Message, error)
+	CreateChannel(ctx context.Context, creator int64, req dto.CreateChannelRequest) (models.Chat, error)
+	Subscribe(ctx context.Context, userID int64, chatID int64) (models.Chat, error)
+	Unsubscribe(ctx context.Context, userID int64, chatID int64) error
 }
 
 type MessageService interface {
-	CreateMessage(ctx context.Context, message dto.Message) (int64, error)
+	SendMessage(ctx context.Context, message dto.Message) (models.Message, moderation.Decision, error)
 }
 
-func NewChatHandler(log *slog.Logger, chatService ChatService, messageService MessageService, appID int32) *ChatHandler {
+type MessageLimiter interface {
+	AllowMessage(ctx context.Context, userID int64, chatID int64, connID string) (ratelimit.Result, error)
+}
+
+type UserProvider interface {
+	GetUsers(ctx context.Context, appID int32, userIDs []int64) (map[int64]models.User, error)
+}
+
+func NewChatHandler(
+	log *slog.Logger,
+	chatService ChatService,
+	messageService MessageService,
+	users UserProvider,
+	limiter MessageLimiter,
+	verifier authMiddleware.TokenVerifier,
+	hub *Hub,
+	avatars *avatar.Store,
+	wsCfg config.Websocket,
+	appID int32,
+) *ChatHandler {
 	return &ChatHandler{
 		log:            log,
 		chatService:    chatService,
 		messageService: messageService,
+		users:          users,
+		limiter:        limiter,
+		verifier:       verifier,
+		tickets:        ticket.NewStore(wsCfg.TicketTTL, wsCfg.MaxTickets),
+		hub:            hub,
+		avatars:        avatars,
+		wsCfg:          wsCfg,
 		appID:          appID,
 	}
 }
 
-func AddChatHandler(log *slog.Logger, chatService ChatService, messageService MessageService, ssoClient *ssogrpc.Client, appID int32) func(r chi.Router) {
-	chatHandler := NewChatHandler(log, chatService, messageService, appID)
+func AddChatHandler(
+	log *slog.Logger,
+	chatService ChatService,
+	messageService MessageService,
+	users UserProvider,
+	limiter MessageLimiter,
+	verifier authMiddleware.TokenVerifier,
+	hub *Hub,
+	avatars *avatar.Store,
+	wsCfg config.Websocket,
+	appID int32,
+) func(r chi.Router) {
+	chatHandler := NewChatHandler(log, chatService, messageService, users, limiter, verifier, hub, avatars, wsCfg, appID)
 
 	return func(r chi.Router) {
-		r.Use(authMiddleware.Auth(log, ssoClient, chatHandler.appID))
-
-		r.Post("/create", chatHandler.CreateChat(context.Background()))
-		r.Get("/{chat_id}", chatHandler.GetChatByID(context.Background()))
-		r.Get("/list", chatHandler.GetUserChats(context.Background()))
-
+		// The websocket upgrade authenticates itself, since browsers cannot
+		// set the Authorization header on it.
 		r.Get("/ws/{chat_id}", chatHandler.ChatWebsocket(context.Background()))
+		// Avatars are loaded by <img> tags, which cannot authenticate either;
+		// their names are random.
+		r.Get("/avatar/{name}", chatHandler.GetAvatar(context.Background()))
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.Auth(log, verifier, chatHandler.appID))
+
+			r.Post("/create", chatHandler.CreateChat(context.Background()))
+			r.Post("/group", chatHandler.CreateGroup(context.Background()))
+			r.Post("/join/{token}", chatHandler.JoinByInvite(context.Background()))
+			r.Post("/channel", chatHandler.CreateChannel(context.Background()))
+			r.Get("/{chat_id}", chatHandler.GetChatByID(context.Background()))
+			r.Patch("/{chat_id}", chatHandler.UpdateChatInfo(context.Background()))
+			r.Post("/{chat_id}/avatar", chatHandler.UploadAvatar(context.Background()))
+			r.Post("/{chat_id}/members", chatHandler.AddMembers(context.Background()))
+			r.Delete("/{chat_id}/members/{user_id}", chatHandler.RemoveMember(context.Background()))
+			r.Put("/{chat_id}/members/{user_id}/role", chatHandler.SetMemberRole(context.Background()))
+			r.Post("/{chat_id}/leave", chatHandler.LeaveChat(context.Background()))
+			r.Post("/{chat_id}/owner", chatHandler.TransferOwnership(context.Background()))
+			r.Get("/{chat_id}/invites", chatHandler.GetInvites(context.Background()))
+			r.Post("/{chat_id}/invites", chatHandler.CreateInvite(context.Background()))
+			r.Delete("/{chat_id}/invites/{invite_id}", chatHandler.RevokeInvite(context.Background()))
+			r.Post("/{chat_id}/subscribe", chatHandler.Subscribe(context.Background()))
+			r.Delete("/{chat_id}/subscribe", chatHandler.Unsubscribe(context.Background()))
+			r.Get("/list", chatHandler.GetUserChats(context.Background()))
+			r.Get("/{chat_id}/settings", chatHandler.GetChatSettings(context.Background()))
+			r.Put("/{chat_id}/settings", chatHandler.UpdateChatSettings(context.Background()))
+			r.Put("/{chat_id}/ttl", chatHandler.SetMessageTTL(context.Background()))
+
+			r.Post("/ws/ticket", chatHandler.WebsocketTicket(context.Background()))
+		})
 	}
 }
 
@@ -78,7 +168,7 @@ func (h *ChatHandler) CreateChat(ctx context.Context) http.HandlerFunc {
 		}
 		if err := req.Validate(); err != nil {
 			h.log.Error("failed to validate request", sl.Err(err))
-			handlers.ErrorResponse(w, r, 422, err.Error())
+			handlers.ValidationErrorResponse(w, r, err)
 			return
 		}
 
@@ -100,16 +190,20 @@ func (h *ChatHandler) CreateChat(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		if !h.checkUsersExist(ctx, w, r, []int64{req.FirstUserID, req.SecondUserID}) {
+			return
+		}
+
 		chatModel := &dto.Chat{
 			FirstUserID:  req.FirstUserID,
 			SecondUserID: req.SecondUserID,
 			UpdatedAt:    time.Now().UTC(),
 		}
 
-		chatID, err := h.chatService.CreateChat(ctx, chatModel)
+		chatID, err := h.chatService.CreateChat(ctx, user.UserID, chatModel)
 		if err != nil {
 			h.log.Error("failed to create chat", sl.Err(err))
-			handlers.ErrorResponse(w, r, 500, "failed to create chat")
+			handlers.ServiceErrorResponse(w, r, err, "failed to create chat")
 			return
 		}
 
@@ -137,13 +231,36 @@ func (h *ChatHandler) GetChatByID(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		if err := h.chatService.CheckMember(ctx, user.UserID, chatID); err != nil {
+			h.log.Error("user is not a chat member", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to check chat membership")
+			return
+		}
+
 		chat, err := h.chatService.GetChatByID(ctx, chatID)
 		if err != nil {
 			h.log.Error("failed to get chat", sl.Err(err))
-			handlers.ErrorResponse(w, r, 404, "chat not found")
+			handlers.ServiceErrorResponse(w, r, err, "failed to get chat")
 			return
 		}
 
+		if handlers.Expand(r, handlers.ExpandParticipants) {
+			chats := []models.Chat{chat}
+			if err := h.attachParticipants(ctx, chats); err != nil {
+				h.log.Error("failed to get chat participants", sl.Err(err))
+				handlers.ErrorResponse(w, r, 503, "failed to get chat participants")
+				return
+			}
+			chat = chats[0]
+		}
+
 		handlers.SuccessResponse(w, r, 200, chat)
 	}
 }
@@ -167,6 +284,18 @@ func (h *ChatHandler) GetUserChats(ctx context.Context) http.HandlerFunc {
 		}
 		h.log.Debug("limit and offset from query", slog.Int("limit", limit), slog.Int("offset", offset))
 
+		hideBlocked, _ := strconv.ParseBool(r.URL.Query().Get("hide_blocked"))
+		filter := dto.ChatFilter{HideBlocked: hideBlocked}
+		if value := r.URL.Query().Get("archived"); value != "" {
+			archived, err := strconv.ParseBool(value)
+			if err != nil {
+				h.log.Error("failed to parse archived", sl.Err(err))
+				handlers.ErrorResponse(w, r, 400, "bad request")
+				return
+			}
+			filter.Archived = &archived
+		}
+
 		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
 		if !ok {
 			h.log.Error("failed to get user")
@@ -174,10 +303,10 @@ func (h *ChatHandler) GetUserChats(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
-		chats, err := h.chatService.GetUserChats(ctx, user.UserID, limit, offset)
+		chats, err := h.chatService.GetUserChats(ctx, user.UserID, filter, limit, offset)
 		if err != nil {
 			h.log.Error("failed to get user chats")
-			handlers.ErrorResponse(w, r, 500, "failed to get user chats")
+			handlers.ServiceErrorResponse(w, r, err, "failed to get user chats")
 			return
 		}
 		if chats == nil {
@@ -185,95 +314,54 @@ func (h *ChatHandler) GetUserChats(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		if handlers.Expand(r, handlers.ExpandParticipants) {
+			if err := h.attachParticipants(ctx, chats); err != nil {
+				h.log.Error("failed to get chat participants", sl.Err(err))
+				handlers.ErrorResponse(w, r, 503, "failed to get chat participants")
+				return
+			}
+		}
+
 		handlers.SuccessResponse(w, r, 200, chats)
 	}
 }
 
-type ChatRoom struct {
-	chatID    int32
-	users     []*websocket.Conn
-	broadcast chan models.Message
-	done      chan bool
-}
-
-var rooms = make(map[int32]*ChatRoom)
+func (h *ChatHandler) GetChatSettings(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.GetChatSettings"
 
-func connectToChatRoom(log *slog.Logger, chatID int32, ws *websocket.Conn) *ChatRoom {
-	room, ok := rooms[chatID]
-	if !ok {
-		room = &ChatRoom{
-			chatID:    chatID,
-			users:     make([]*websocket.Conn, 0),
-			broadcast: make(chan models.Message),
-			done:      make(chan bool),
-		}
-		rooms[chatID] = room
-	}
-	go broadcast(log, room)
-	room.users = append(room.users, ws)
-	return room
-}
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
 
-func broadcast(log *slog.Logger, room *ChatRoom) {
-	for {
-		select {
-		case msg := <-room.broadcast:
-			for _, user := range room.users {
-				go func(ws *websocket.Conn) {
-					err := ws.WriteJSON(msg)
-					if err != nil {
-						log.Error("failed to write message to websocket", sl.Err(err))
-					}
-				}(user)
-			}
-		case <-room.done:
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
 			return
 		}
-	}
-}
-
-func findWebsocketIndex(log *slog.Logger, ws *websocket.Conn, users []*websocket.Conn) (int, error) {
-	const op = "handlers.chat.findWebsocketIndex"
 
-	for idx, conn := range users {
-		if conn == ws {
-			return idx, nil
-		}
-	}
-	log.Error("failed to find websocket in chat room", slog.String("op", op))
-	return 0, errors.New("websocket not found in chat room")
-}
-
-func disconnectFromChatRoom(log *slog.Logger, chatID int32, ws *websocket.Conn) {
-	const op = "handlers.chat.disconnectFromChatRoom"
-
-	defer ws.Close()
-
-	room, ok := rooms[chatID]
-	if ok {
-		if len(room.users) == 1 {
-			room.done <- true
-			delete(rooms, chatID)
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
 			return
 		}
-		wsIdx, err := findWebsocketIndex(log, ws, room.users)
+
+		settings, err := h.chatService.GetChatSettings(ctx, user.UserID, chatID)
 		if err != nil {
-			log.Error("failed to find websocket in chat room", sl.OpErr(op, err))
+			h.log.Error("failed to get chat settings", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to get chat settings")
 			return
 		}
-		room.users = append(room.users[:wsIdx], room.users[wsIdx+1:]...)
-		return
+
+		handlers.SuccessResponse(w, r, 200, settings)
 	}
 }
 
-func (h *ChatHandler) ChatWebsocket(ctx context.Context) http.HandlerFunc {
-	const op = "handlers.chat.ChatWebsocket"
-
-	upgrader := websocket.Upgrader{
-		ReadBufferSize:    1024,
-		WriteBufferSize:   1024,
-		EnableCompression: true,
-	}
+func (h *ChatHandler) UpdateChatSettings(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.UpdateChatSettings"
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		h.log = h.log.With(
@@ -281,13 +369,25 @@ func (h *ChatHandler) ChatWebsocket(ctx context.Context) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 32)
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
 		if err != nil {
 			h.log.Error("failed to parse chat_id", sl.Err(err))
 			handlers.ErrorResponse(w, r, 400, "bad request")
 			return
 		}
 
+		var req dto.ChatSettingsRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
 		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
 		if !ok {
 			h.log.Error("failed to get user")
@@ -295,78 +395,97 @@ func (h *ChatHandler) ChatWebsocket(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
-		h.log.Debug("new connection to chat websocket")
-		ws, err := upgrader.Upgrade(w, r, nil)
+		settings, err := h.chatService.UpdateChatSettings(ctx, user.UserID, chatID, req)
 		if err != nil {
-			h.log.Error("failed to upgrade connection", sl.Err(err))
+			h.log.Error("failed to update chat settings", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to update chat settings")
 			return
 		}
 
-		room := connectToChatRoom(h.log, int32(chatID), ws)
-
-		for {
-			var mes dto.MessageRequest
-			if err := ws.ReadJSON(&mes); err != nil {
-				h.log.Error("failed to read message", sl.Err(err))
-				disconnectFromChatRoom(h.log, int32(chatID), ws)
-				h.log.Debug("chat room users", slog.Int64("chat_id", chatID), "rooms", rooms)
-				break
-			}
-
-			mes.ChatID = chatID
+		handlers.SuccessResponse(w, r, 200, settings)
+	}
+}
 
-			h.log.Debug("message received", slog.Any("message", mes))
-			h.log.Debug("chat room users", slog.Int64("chat_id", chatID), "rooms", rooms)
+// checkUsersExist resolves the users and, when some of them do not exist or
+// cannot be resolved, writes the error response and reports false.
+func (h *ChatHandler) checkUsersExist(ctx context.Context, w http.ResponseWriter, r *http.Request, userIDs []int64) bool {
+	if len(userIDs) == 0 {
+		return true
+	}
 
-			mesModel, err := h.sendMessageAndUpdateChat(ctx, mes, user.UserID)
-			if err != nil {
-				h.log.Error("failed to send message and update chat", sl.OpErr(op, err))
-				continue
-			}
+	users, err := h.users.GetUsers(ctx, h.appID, userIDs)
+	if err != nil {
+		h.log.Error("failed to get chat participants", sl.Err(err))
+		handlers.ErrorResponse(w, r, 503, "failed to validate chat participants")
+		return false
+	}
 
-			room.broadcast <- mesModel
+	var unknown []int64
+	for _, userID := range userIDs {
+		if _, ok := users[userID]; !ok {
+			unknown = append(unknown, userID)
 		}
-
-		h.log.Debug("connection closed")
 	}
-}
+	if len(unknown) > 0 {
+		h.log.Error("chat participants not found", slog.Any("user_ids", unknown))
+		handlers.CodedErrorResponse(w, r, 422, handlers.CodeUsersNotFound, map[string]any{
+			"message":  "users not found",
+			"user_ids": unknown,
+		})
+		return false
+	}
 
-func (h *ChatHandler) sendMessageAndUpdateChat(ctx context.Context, mes dto.MessageRequest, sender int64) (models.Message, error) {
-	const op = "handlers.chat.sendMessageAndUpdateChat"
+	return true
+}
 
-	if err := mes.Validate(); err != nil {
-		h.log.Error("failed to validate message", sl.OpErr(op, err))
-		return models.Message{}, err
+// attachParticipants fills in the profiles of the chats' participants: both
+// users of a direct chat and the members of a group. Group members are only
+// loaded for a single chat, so groups in a chat list are left as they are.
+func (h *ChatHandler) attachParticipants(ctx context.Context, chats []models.Chat) error {
+	var userIDs []int64
+	for _, chat := range chats {
+		userIDs = append(userIDs, participantIDs(chat)...)
 	}
-
-	createdAt := time.Now().UTC()
-	messageModel := dto.Message{
-		ChatID:    mes.ChatID,
-		Sender:    sender,
-		Text:      mes.Text,
-		CreatedAt: createdAt,
+	if len(userIDs) == 0 {
+		return nil
 	}
-	if err := messageModel.Validate(); err != nil {
-		h.log.Error("failed to validate message", sl.OpErr(op, err))
-		return models.Message{}, err
+
+	users, err := h.users.GetUsers(ctx, h.appID, userIDs)
+	if err != nil {
+		return err
 	}
 
-	messadeID, err := h.messageService.CreateMessage(ctx, messageModel)
-	if err != nil || messadeID <= 0 {
-		h.log.Error("failed to create message", sl.OpErr(op, err))
-		return models.Message{}, err
+	for idx := range chats {
+		ids := participantIDs(chats[idx])
+		if len(ids) == 0 {
+			continue
+		}
+		chats[idx].Participants = make([]models.User, 0, len(ids))
+		for _, userID := range ids {
+			chats[idx].Participants = append(chats[idx].Participants, userProfile(users, userID))
+		}
 	}
+	return nil
+}
 
-	if err := h.chatService.UpdateChatMessage(ctx, mes.ChatID, mes.Text, createdAt); err != nil {
-		h.log.Error("failed to update chat message", sl.OpErr(op, err))
-		return models.Message{}, err
+func participantIDs(chat models.Chat) []int64 {
+	switch chat.Type {
+	case models.ChatDirect:
+		return []int64{chat.FirstUserID, chat.SecondUserID}
+	case models.ChatGroup:
+		ids := make([]int64, 0, len(chat.Members))
+		for _, member := range chat.Members {
+			ids = append(ids, member.UserID)
+		}
+		return ids
+	default:
+		return nil
 	}
+}
 
-	return models.Message{
-		ID:        messadeID,
-		ChatID:    mes.ChatID,
-		Sender:    sender,
-		Text:      mes.Text,
-		CreatedAt: createdAt,
-	}, nil
+func userProfile(users map[int64]models.User, userID int64) models.User {
+	if user, ok := users[userID]; ok {
+		return user
+	}
+	return models.User{UserID: userID}
 }
@@ -0,0 +1,325 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"simple-chat/internal/config"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	errSendBufferFull = errors.New("websocket send buffer is full")
+	ErrHubClosed      = errors.New("websocket hub is shutting down")
+)
+
+// wsConn is a websocket connection of an authenticated user. All frames are
+// written by its write pump, since gorilla allows only one concurrent writer.
+type wsConn struct {
+	id     string
+	ws     *websocket.Conn
+	user   models.User
+	chatID int64
+	cfg    config.Websocket
+	// lang is the Accept-Language of the upgrade request, used to localize
+	// error frames.
+	lang string
+
+	send      chan any
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mu    sync.Mutex
+	token string
+}
+
+func newWSConn(id string, ws *websocket.Conn, user models.User, token string, chatID int64, cfg config.Websocket) *wsConn {
+	return &wsConn{
+		id:     id,
+		ws:     ws,
+		user:   user,
+		chatID: chatID,
+		cfg:    cfg,
+		send:   make(chan any, cfg.SendBuffer),
+		closed: make(chan struct{}),
+		token:  token,
+	}
+}
+
+// enqueue schedules a frame for writing without blocking. A connection that
+// cannot keep up is closed rather than stalling its room.
+func (c *wsConn) enqueue(v any) error {
+	select {
+	case <-c.closed:
+		return websocket.ErrCloseSent
+	default:
+	}
+
+	select {
+	case c.send <- v:
+		return nil
+	default:
+		c.closeWith(websocket.CloseTryAgainLater, "too slow")
+		return errSendBufferFull
+	}
+}
+
+// writePump writes queued frames and pings until the connection is closed.
+func (c *wsConn) writePump(log *slog.Logger) {
+	const op = "handlers.chat.writePump"
+
+	ticker := time.NewTicker(c.cfg.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case v := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+			var err error
+			if frame, ok := v.(*websocket.PreparedMessage); ok {
+				err = c.ws.WritePreparedMessage(frame)
+			} else {
+				err = c.ws.WriteJSON(v)
+			}
+			if err != nil {
+				log.Error("failed to write to websocket", sl.OpErr(op, err))
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.cfg.WriteTimeout)); err != nil {
+				log.Error("failed to ping websocket", sl.OpErr(op, err))
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// prepareRead applies the frame size limit and the pong based read deadline.
+func (c *wsConn) prepareRead() {
+	c.ws.SetReadLimit(c.cfg.MaxMessageSize)
+	c.ws.SetReadDeadline(time.Now().Add(c.cfg.PongTimeout))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(c.cfg.PongTimeout))
+	})
+}
+
+func (c *wsConn) closeWith(code int, reason string) {
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(c.cfg.WriteTimeout))
+	c.close()
+}
+
+func (c *wsConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.ws.Close()
+	})
+}
+
+func (c *wsConn) currentToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token
+}
+
+func (c *wsConn) setToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = token
+}
+
+// Hub keeps track of the websocket connections of every chat room. The HTTP
+// server does not track hijacked connections, so the hub also drains them on
+// shutdown.
+type Hub struct {
+	log     *slog.Logger
+	mu      sync.RWMutex
+	rooms   map[int64]map[*wsConn]struct{}
+	closing bool
+	sends   sync.WaitGroup
+}
+
+func NewHub(log *slog.Logger) *Hub {
+	return &Hub{
+		log:   log,
+		rooms: make(map[int64]map[*wsConn]struct{}),
+	}
+}
+
+func (h *Hub) Register(conn *wsConn) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closing {
+		return ErrHubClosed
+	}
+
+	room, ok := h.rooms[conn.chatID]
+	if !ok {
+		room = make(map[*wsConn]struct{})
+		h.rooms[conn.chatID] = room
+	}
+	room[conn] = struct{}{}
+	return nil
+}
+
+// Accepting reports whether new connections may be upgraded.
+func (h *Hub) Accepting() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return !h.closing
+}
+
+// BeginSend marks the start of a message write, so that shutdown can wait for
+// it. It reports false once the hub is shutting down; otherwise the caller
+// must call EndSend.
+func (h *Hub) BeginSend() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.closing {
+		return false
+	}
+	h.sends.Add(1)
+	return true
+}
+
+func (h *Hub) EndSend() {
+	h.sends.Done()
+}
+
+// Shutdown stops accepting connections, tells every connected client that the
+// server is going away and waits for in-flight message writes until ctx is
+// done.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	var conns []*wsConn
+	for _, room := range h.rooms {
+		for conn := range room {
+			conns = append(conns, conn)
+		}
+	}
+	h.mu.Unlock()
+
+	h.log.Info("closing websocket connections", slog.Int("count", len(conns)))
+	for _, conn := range conns {
+		conn.closeWith(websocket.CloseGoingAway, "server going away")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.sends.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unregister removes the connection from its room, dropping empty rooms, and
+// closes it.
+func (h *Hub) Unregister(conn *wsConn) {
+	h.mu.Lock()
+	room, ok := h.rooms[conn.chatID]
+	if ok {
+		delete(room, conn)
+		if len(room) == 0 {
+			delete(h.rooms, conn.chatID)
+		}
+	}
+	h.mu.Unlock()
+
+	conn.close()
+}
+
+// Broadcast sends event to every connection in the chat room. The frame is
+// encoded once and shared by all connections, which keeps fan-out to large
+// rooms such as channels cheap.
+func (h *Hub) Broadcast(chatID int64, event any) {
+	const op = "handlers.chat.Hub.Broadcast"
+
+	h.mu.RLock()
+	conns := make([]*wsConn, 0, len(h.rooms[chatID]))
+	for conn := range h.rooms[chatID] {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+
+	if len(conns) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		h.log.Error("failed to encode websocket frame", slog.Int64("chat_id", chatID), sl.OpErr(op, err))
+		return
+	}
+	frame, err := websocket.NewPreparedMessage(websocket.TextMessage, data)
+	if err != nil {
+		h.log.Error("failed to prepare websocket frame", slog.Int64("chat_id", chatID), sl.OpErr(op, err))
+		return
+	}
+
+	for _, conn := range conns {
+		if err := conn.enqueue(frame); err != nil {
+			h.log.Error("failed to queue websocket frame",
+				slog.Int64("chat_id", chatID),
+				slog.Int64("user_id", conn.user.UserID),
+				sl.OpErr(op, err),
+			)
+		}
+	}
+}
+
+// BroadcastMessage sends a message that was not sent over the websocket,
+// such as a scheduled one, to the chat room.
+func (h *Hub) BroadcastMessage(message models.Message) {
+	h.Broadcast(message.ChatID, messageEvent{Type: eventMessage, Message: message})
+}
+
+// BroadcastDeleted tells the chat room that the messages were deleted, such
+// as after they expired.
+func (h *Hub) BroadcastDeleted(chatID int64, messageIDs []int64) {
+	h.Broadcast(chatID, deletedEvent{Type: eventDeleted, ChatID: chatID, MessageIDs: messageIDs})
+}
+
+// Kick closes the user's connections to the chat, such as after they were
+// removed from it.
+func (h *Hub) Kick(chatID int64, userID int64) {
+	h.mu.RLock()
+	var conns []*wsConn
+	for conn := range h.rooms[chatID] {
+		if conn.user.UserID == userID {
+			conns = append(conns, conn)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, conn := range conns {
+		conn.closeWith(closeRemoved, "removed from chat")
+	}
+}
+
+func (h *Hub) RoomSize(chatID int64) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.rooms[chatID])
+}
@@ -0,0 +1,229 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/logger/sl"
+	authMiddleware "simple-chat/internal/lib/middleware"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// avatarPath is where uploaded avatars are served from.
+const avatarPath = "/chat/avatar/"
+
+func (h *ChatHandler) UpdateChatInfo(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.UpdateChatInfo"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		var req dto.ChatInfoRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		chat, err := h.updateChatInfo(ctx, user.UserID, chatID, req)
+		if err != nil {
+			h.log.Error("failed to update chat info", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to update chat")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 200, chat)
+	}
+}
+
+// UploadAvatar stores the image sent in the "avatar" multipart field and
+// makes it the chat's avatar.
+func (h *ChatHandler) UploadAvatar(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.UploadAvatar"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		// Leave room for the multipart framing around the file.
+		r.Body = http.MaxBytesReader(w, r.Body, h.avatars.MaxSize()+64<<10)
+		file, _, err := r.FormFile("avatar")
+		if err != nil {
+			h.log.Error("failed to read avatar", sl.Err(err))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				handlers.CodedErrorResponse(w, r, 413, handlers.CodeAvatarTooLarge, "avatar is too large")
+				return
+			}
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		defer file.Close()
+
+		name, err := h.avatars.Save(file)
+		if err != nil {
+			h.log.Error("failed to save avatar", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to save avatar")
+			return
+		}
+
+		url := avatarPath + name
+		chat, err := h.updateChatInfo(ctx, user.UserID, chatID, dto.ChatInfoRequest{AvatarURL: &url})
+		if err != nil {
+			h.log.Error("failed to update chat avatar", sl.Err(err))
+			if err := h.avatars.Remove(name); err != nil {
+				h.log.Error("failed to remove avatar", sl.Err(err))
+			}
+			handlers.ServiceErrorResponse(w, r, err, "failed to update chat")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 200, chat)
+	}
+}
+
+func (h *ChatHandler) GetAvatar(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.GetAvatar"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		path, err := h.avatars.Path(chi.URLParam(r, "name"))
+		if err != nil {
+			handlers.ErrorResponse(w, r, 404, "avatar not found")
+			return
+		}
+
+		w.Header().Set("Cache-Control", "public, max-age=86400, immutable")
+		http.ServeFile(w, r, path)
+	}
+}
+
+// updateChatInfo applies the change, removes an uploaded avatar that is no
+// longer used and tells the connected members about the new chat info.
+func (h *ChatHandler) updateChatInfo(ctx context.Context, userID int64, chatID int64, info dto.ChatInfoRequest) (models.Chat, error) {
+	const op = "handlers.chat.updateChatInfo"
+
+	previous, err := h.chatService.GetChatByID(ctx, chatID)
+	if err != nil {
+		return models.Chat{}, err
+	}
+
+	chat, err := h.chatService.UpdateChatInfo(ctx, userID, chatID, info)
+	if err != nil {
+		return models.Chat{}, err
+	}
+
+	if previous.AvatarURL != chat.AvatarURL && strings.HasPrefix(previous.AvatarURL, avatarPath) {
+		if err := h.avatars.Remove(strings.TrimPrefix(previous.AvatarURL, avatarPath)); err != nil {
+			h.log.Error("failed to remove previous avatar", sl.OpErr(op, err))
+		}
+	}
+
+	h.hub.Broadcast(chatID, chatEvent{Type: eventChatUpdated, Chat: chat})
+
+	return chat, nil
+}
+
+func (h *ChatHandler) SetMessageTTL(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.SetMessageTTL"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		var req dto.MessageTTLRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		message, err := h.chatService.SetMessageTTL(ctx, user.UserID, chatID, req.TTL)
+		if err != nil {
+			h.log.Error("failed to set message ttl", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to set message ttl")
+			return
+		}
+		if message.ID != 0 {
+			h.hub.Broadcast(chatID, messageEvent{Type: eventMessage, Message: message})
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message": "message ttl successfully changed",
+			"chat_id": chatID,
+			"ttl":     req.TTL,
+		})
+	}
+}
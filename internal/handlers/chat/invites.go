@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/logger/sl"
+	authMiddleware "simple-chat/internal/lib/middleware"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+func (h *ChatHandler) CreateInvite(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.CreateInvite"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		var req dto.CreateInviteRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		invite, err := h.chatService.CreateInvite(ctx, user.UserID, chatID, req)
+		if err != nil {
+			h.log.Error("failed to create invite", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to create invite")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 201, invite)
+	}
+}
+
+func (h *ChatHandler) GetInvites(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.GetInvites"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		invites, err := h.chatService.GetInvites(ctx, user.UserID, chatID)
+		if err != nil {
+			h.log.Error("failed to get invites", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to get invites")
+			return
+		}
+		if invites == nil {
+			invites = []models.Invite{}
+		}
+
+		handlers.SuccessResponse(w, r, 200, invites)
+	}
+}
+
+func (h *ChatHandler) RevokeInvite(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.RevokeInvite"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		inviteID, err := strconv.ParseInt(chi.URLParam(r, "invite_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse invite_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		if err := h.chatService.RevokeInvite(ctx, user.UserID, chatID, inviteID); err != nil {
+			h.log.Error("failed to revoke invite", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to revoke invite")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message":   "invite successfully revoked",
+			"invite_id": inviteID,
+		})
+	}
+}
+
+func (h *ChatHandler) JoinByInvite(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.JoinByInvite"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		message, err := h.chatService.JoinByInvite(ctx, user.UserID, chi.URLParam(r, "token"))
+		if err != nil {
+			h.log.Error("failed to join chat", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to join chat")
+			return
+		}
+		if message.ID != 0 {
+			h.hub.Broadcast(message.ChatID, messageEvent{Type: eventMessage, Message: message})
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message": "chat successfully joined",
+			"chat_id": message.ChatID,
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package chat
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/logger/sl"
+	authMiddleware "simple-chat/internal/lib/middleware"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+func (h *ChatHandler) CreateGroup(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.CreateGroup"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req dto.CreateGroupRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		if !h.checkUsersExist(ctx, w, r, req.Members) {
+			return
+		}
+
+		chat, err := h.chatService.CreateGroup(ctx, user.UserID, req)
+		if err != nil {
+			h.log.Error("failed to create group", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to create group")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 201, chat)
+	}
+}
+
+func (h *ChatHandler) AddMembers(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.AddMembers"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		var req dto.AddMembersRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		if !h.checkUsersExist(ctx, w, r, req.UserIDs) {
+			return
+		}
+
+		messages, err := h.chatService.AddMembers(ctx, user.UserID, chatID, req.UserIDs)
+		if err != nil {
+			h.log.Error("failed to add members", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to add members")
+			return
+		}
+		for _, message := range messages {
+			h.hub.Broadcast(chatID, messageEvent{Type: eventMessage, Message: message})
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message":  "members successfully added",
+			"user_ids": req.UserIDs,
+		})
+	}
+}
+
+func (h *ChatHandler) RemoveMember(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.RemoveMember"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, userID, ok := h.memberParams(w, r)
+		if !ok {
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		message, err := h.chatService.RemoveMember(ctx, user.UserID, chatID, userID)
+		if err != nil {
+			h.log.Error("failed to remove member", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to remove member")
+			return
+		}
+		h.hub.Kick(chatID, userID)
+		h.hub.Broadcast(chatID, messageEvent{Type: eventMessage, Message: message})
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message": "member successfully removed",
+			"user_id": userID,
+		})
+	}
+}
+
+func (h *ChatHandler) SetMemberRole(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.SetMemberRole"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, userID, ok := h.memberParams(w, r)
+		if !ok {
+			return
+		}
+
+		var req dto.MemberRoleRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		message, err := h.chatService.SetMemberRole(ctx, user.UserID, chatID, userID, req.Role)
+		if err != nil {
+			h.log.Error("failed to set member role", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to set member role")
+			return
+		}
+		// An unchanged role records nothing.
+		if message.ID != 0 {
+			h.hub.Broadcast(chatID, messageEvent{Type: eventMessage, Message: message})
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message": "member role successfully changed",
+			"user_id": userID,
+			"role":    req.Role,
+		})
+	}
+}
+
+func (h *ChatHandler) LeaveChat(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.LeaveChat"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		message, err := h.chatService.LeaveChat(ctx, user.UserID, chatID)
+		if err != nil {
+			h.log.Error("failed to leave chat", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to leave chat")
+			return
+		}
+		h.hub.Kick(chatID, user.UserID)
+		h.hub.Broadcast(chatID, messageEvent{Type: eventMessage, Message: message})
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message": "chat successfully left",
+			"chat_id": chatID,
+		})
+	}
+}
+
+func (h *ChatHandler) TransferOwnership(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.TransferOwnership"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		var req dto.TransferOwnershipRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		message, err := h.chatService.TransferOwnership(ctx, user.UserID, chatID, req.UserID)
+		if err != nil {
+			h.log.Error("failed to transfer ownership", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to transfer ownership")
+			return
+		}
+		if message.ID != 0 {
+			h.hub.Broadcast(chatID, messageEvent{Type: eventMessage, Message: message})
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message": "ownership successfully transferred",
+			"user_id": req.UserID,
+		})
+	}
+}
+
+func (h *ChatHandler) memberParams(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+	if err != nil {
+		h.log.Error("failed to parse chat_id", sl.Err(err))
+		handlers.ErrorResponse(w, r, 400, "bad request")
+		return 0, 0, false
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+	if err != nil {
+		h.log.Error("failed to parse user_id", sl.Err(err))
+		handlers.ErrorResponse(w, r, 400, "bad request")
+		return 0, 0, false
+	}
+
+	return chatID, userID, true
+}
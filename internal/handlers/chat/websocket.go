@@ -0,0 +1,389 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"simple-chat/internal/clients/sso"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/logger/sl"
+	authMiddleware "simple-chat/internal/lib/middleware"
+	"simple-chat/internal/lib/moderation"
+	"simple-chat/internal/validator"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/websocket"
+)
+
+// bearerSubprotocol lets browsers pass an access token in the
+// Sec-WebSocket-Protocol header as "bearer, <token>".
+const bearerSubprotocol = "bearer"
+
+const (
+	eventMessage     = "message"
+	eventReauth      = "reauth"
+	eventError       = "error"
+	eventModeration  = "moderation"
+	eventChatUpdated = "chat_updated"
+	eventDeleted     = "messages_deleted"
+
+	// closeAuthExpired is sent when the connection's credentials expired or
+	// were revoked.
+	closeAuthExpired = 4001
+	// closeRemoved is sent when the user left or was removed from the chat.
+	closeRemoved = 4003
+)
+
+type messageEvent struct {
+	Type string `json:"type"`
+	models.Message
+}
+
+type chatEvent struct {
+	Type string      `json:"type"`
+	Chat models.Chat `json:"chat"`
+}
+
+type deletedEvent struct {
+	Type       string  `json:"type"`
+	ChatID     int64   `json:"chat_id"`
+	MessageIDs []int64 `json:"message_ids"`
+}
+
+type statusEvent struct {
+	Type   string `json:"type"`
+	Status string `json:"status,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// RetryAfter is the number of seconds to wait before retrying.
+	RetryAfter int                    `json:"retry_after,omitempty"`
+	Errors     []validator.FieldError `json:"errors,omitempty"`
+}
+
+// sendErrorEvent describes a failed send with the same codes the REST API
+// uses, translating validation errors to lang.
+func sendErrorEvent(err error, lang string) statusEvent {
+	apiErr := handlers.ServiceError(err, "failed to send message")
+	event := statusEvent{
+		Type:   eventError,
+		Code:   apiErr.Code,
+		Detail: fmt.Sprint(apiErr.Detail),
+		Reason: apiErr.Reason,
+	}
+
+	if apiErr.Fields != nil {
+		trans := validator.TranslatorFor(lang)
+		event.Errors = trans.Errors(apiErr.Fields)
+		event.Detail = trans.Summary(event.Errors)
+	}
+
+	return event
+}
+
+func (h *ChatHandler) ChatWebsocket(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.ChatWebsocket"
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: true,
+		CheckOrigin:       h.checkOrigin,
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		chatID, err := strconv.ParseInt(chi.URLParam(r, "chat_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse chat_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		if !h.hub.Accepting() {
+			h.log.Error("websocket hub is shutting down")
+			handlers.ErrorResponse(w, r, 503, "server is shutting down")
+			return
+		}
+
+		user, token, responseHeader, err := h.authenticateWebsocket(r)
+		if err != nil {
+			h.log.Error("unauthorized", sl.Err(err))
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		if err := h.chatService.CheckMember(ctx, user.UserID, chatID); err != nil {
+			h.log.Error("websocket user is not a chat member", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to check chat membership")
+			return
+		}
+
+		h.log.Debug("new connection to chat websocket")
+		ws, err := upgrader.Upgrade(w, r, responseHeader)
+		if err != nil {
+			h.log.Error("failed to upgrade connection", sl.Err(err))
+			return
+		}
+
+		conn := newWSConn(middleware.GetReqID(r.Context()), ws, user, token, chatID, h.wsCfg)
+		conn.lang = r.Header.Get("Accept-Language")
+		if err := h.hub.Register(conn); err != nil {
+			h.log.Error("failed to register websocket", sl.Err(err))
+			conn.closeWith(websocket.CloseGoingAway, "server going away")
+			return
+		}
+		defer h.hub.Unregister(conn)
+
+		go conn.writePump(h.log)
+		go h.revalidate(conn)
+
+		conn.prepareRead()
+		for {
+			var frame dto.WebsocketFrame
+			if err := ws.ReadJSON(&frame); err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					h.log.Error("failed to read message", sl.Err(err))
+				}
+				break
+			}
+
+			if frame.Type == dto.FrameReauth {
+				h.reauthenticate(conn, frame.Token)
+				continue
+			}
+
+			mes := dto.MessageRequest{
+				ChatID: chatID,
+				Text:   frame.Text,
+			}
+
+			h.log.Debug("message received", slog.Any("message", mes))
+			h.log.Debug("chat room users", slog.Int64("chat_id", chatID), slog.Int("count", h.hub.RoomSize(chatID)))
+
+			// Membership was checked before the upgrade and removed members
+			// are kicked, so only members reach the chat's limit here.
+			limit, err := h.limiter.AllowMessage(ctx, user.UserID, chatID, conn.id)
+			if err != nil {
+				h.log.Error("failed to check message rate limit", sl.OpErr(op, err))
+			} else if !limit.Allowed {
+				h.log.Error("message rate limit exceeded", slog.Int64("user_id", user.UserID))
+				conn.enqueue(statusEvent{
+					Type:       eventError,
+					Code:       handlers.CodeRateLimited,
+					Detail:     "too many messages",
+					RetryAfter: handlers.RetryAfterSeconds(limit.RetryAfter),
+				})
+				continue
+			}
+
+			if !h.hub.BeginSend() {
+				break
+			}
+			mesModel, decision, err := h.sendMessage(ctx, mes, user.UserID)
+			h.hub.EndSend()
+			if err != nil {
+				h.log.Error("failed to send message", sl.OpErr(op, err))
+				conn.enqueue(sendErrorEvent(err, conn.lang))
+				continue
+			}
+			if decision.Action != moderation.Allow {
+				conn.enqueue(statusEvent{Type: eventModeration, Status: string(decision.Action), Reason: decision.Reason})
+			}
+
+			h.hub.Broadcast(chatID, messageEvent{Type: eventMessage, Message: mesModel})
+		}
+
+		h.log.Debug("connection closed")
+	}
+}
+
+// revalidate periodically checks the connection's token against the verifier
+// and closes the connection once it is rejected. Failures to reach SSO keep
+// the connection open.
+func (h *ChatHandler) revalidate(conn *wsConn) {
+	const op = "handlers.chat.revalidate"
+
+	if h.wsCfg.ReauthInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(h.wsCfg.ReauthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-conn.closed:
+			return
+		case <-ticker.C:
+			_, err := h.verifier.CurrentUser(context.Background(), conn.currentToken(), h.appID)
+			if err == nil {
+				continue
+			}
+			if errors.Is(err, sso.ErrUnavailable) {
+				h.log.Error("failed to revalidate websocket credentials", sl.OpErr(op, err))
+				continue
+			}
+
+			h.log.Info("websocket credentials are no longer valid",
+				slog.Int64("user_id", conn.user.UserID),
+				sl.OpErr(op, err),
+			)
+			conn.closeWith(closeAuthExpired, "authentication expired")
+			return
+		}
+	}
+}
+
+// reauthenticate replaces the connection's token with a refreshed one sent
+// in a "reauth" frame. The token must belong to the same user.
+func (h *ChatHandler) reauthenticate(conn *wsConn, token string) {
+	const op = "handlers.chat.reauthenticate"
+
+	user, err := h.verifier.CurrentUser(context.Background(), token, h.appID)
+	if err != nil || user.UserID != conn.user.UserID {
+		if err == nil {
+			err = errors.New("token belongs to another user")
+		}
+		h.log.Error("failed to reauthenticate websocket", sl.OpErr(op, err))
+		if err := conn.enqueue(statusEvent{Type: eventError, Code: "reauth_failed", Detail: "invalid token"}); err != nil {
+			h.log.Error("failed to write to websocket", sl.OpErr(op, err))
+		}
+		return
+	}
+
+	conn.setToken(token)
+	if err := conn.enqueue(statusEvent{Type: eventReauth, Status: "ok"}); err != nil {
+		h.log.Error("failed to write to websocket", sl.OpErr(op, err))
+	}
+}
+
+func (h *ChatHandler) WebsocketTicket(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.chat.WebsocketTicket"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		id, err := h.tickets.Issue(user, authMiddleware.BearerToken(r))
+		if err != nil {
+			h.log.Error("failed to issue websocket ticket", sl.Err(err))
+			handlers.ErrorResponse(w, r, 500, "failed to issue websocket ticket")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 201, map[string]any{
+			"ticket":     id,
+			"expires_in": int(h.tickets.TTL().Seconds()),
+		})
+	}
+}
+
+// authenticateWebsocket resolves the user and token of a websocket upgrade
+// request from, in order, a single-use "ticket" query parameter, a "bearer"
+// subprotocol or the Authorization header. When the subprotocol is used, the
+// returned header echoes it back as the handshake requires.
+func (h *ChatHandler) authenticateWebsocket(r *http.Request) (models.User, string, http.Header, error) {
+	if id := r.URL.Query().Get("ticket"); id != "" {
+		t, err := h.tickets.Redeem(id)
+		if err != nil {
+			return models.User{}, "", nil, err
+		}
+		return t.User, t.Token, nil, nil
+	}
+
+	if token, ok := subprotocolToken(r); ok {
+		user, err := h.verifier.CurrentUser(r.Context(), token, h.appID)
+		if err != nil {
+			return models.User{}, "", nil, err
+		}
+		return user, token, http.Header{"Sec-WebSocket-Protocol": {bearerSubprotocol}}, nil
+	}
+
+	if token := authMiddleware.BearerToken(r); token != "" {
+		user, err := h.verifier.CurrentUser(r.Context(), token, h.appID)
+		if err != nil {
+			return models.User{}, "", nil, err
+		}
+		return user, token, nil, nil
+	}
+
+	return models.User{}, "", nil, errors.New("no websocket credentials provided")
+}
+
+func subprotocolToken(r *http.Request) (string, bool) {
+	protocols := websocket.Subprotocols(r)
+	for idx, protocol := range protocols {
+		if protocol == bearerSubprotocol && idx+1 < len(protocols) {
+			return protocols[idx+1], true
+		}
+	}
+	return "", false
+}
+
+// checkOrigin allows requests without an Origin header (non-browser clients)
+// and otherwise requires a match against the configured allowed origins,
+// which may contain "*" wildcards. With no origins configured only
+// same-origin requests are accepted.
+func (h *ChatHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if len(h.wsCfg.AllowedOrigins) == 0 {
+		return strings.EqualFold(strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://"), r.Host)
+	}
+
+	for _, allowed := range h.wsCfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if ok, _ := path.Match(strings.ToLower(allowed), strings.ToLower(origin)); ok {
+			return true
+		}
+	}
+
+	h.log.Error("websocket origin is not allowed", slog.String("origin", origin))
+	return false
+}
+
+// sendMessage validates a websocket message and sends it through the message
+// service.
+func (h *ChatHandler) sendMessage(ctx context.Context, mes dto.MessageRequest, sender int64) (models.Message, moderation.Decision, error) {
+	const op = "handlers.chat.sendMessage"
+
+	if err := mes.Validate(); err != nil {
+		h.log.Error("failed to validate message", sl.OpErr(op, err))
+		return models.Message{}, moderation.Decision{}, err
+	}
+
+	return h.messageService.SendMessage(ctx, dto.Message{
+		ChatID:    mes.ChatID,
+		Sender:    sender,
+		Text:      mes.Text,
+		CreatedAt: time.Now().UTC(),
+	})
+}
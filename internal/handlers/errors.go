@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"simple-chat/internal/lib/avatar"
+	"simple-chat/internal/lib/moderation"
+	chatService "simple-chat/internal/services/chat"
+	messageService "simple-chat/internal/services/message"
+	userService "simple-chat/internal/services/user"
+	chatStorage "simple-chat/internal/storage/chat"
+	inviteStorage "simple-chat/internal/storage/invite"
+	messageStorage "simple-chat/internal/storage/message"
+	reportStorage "simple-chat/internal/storage/report"
+	scheduledStorage "simple-chat/internal/storage/scheduled"
+	userStorage "simple-chat/internal/storage/user"
+	"simple-chat/internal/validator"
+)
+
+// Error codes are stable, machine-readable identifiers of a failure, unlike
+// the human-readable detail.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeValidationFailed = "validation_failed"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+	CodeUnavailable      = "unavailable"
+
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeInvalidToken       = "invalid_token"
+	CodeUserExists         = "user_exists"
+	CodeAuthUnavailable    = "auth_unavailable"
+
+	CodeChatNotFound    = "chat_not_found"
+	CodeMessageNotFound = "message_not_found"
+	CodeUsersNotFound   = "users_not_found"
+
+	CodeMessageRejected = "message_rejected"
+
+	CodeReportNotFound     = "report_not_found"
+	CodeSuspensionNotFound = "suspension_not_found"
+	CodeNotChatMember      = "not_chat_member"
+	CodeUserSuspended      = "user_suspended"
+
+	CodeUserBlocked     = "user_blocked"
+	CodeBlockNotFound   = "block_not_found"
+	CodeCannotBlockSelf = "cannot_block_self"
+
+	CodeAvatarTooLarge    = "avatar_too_large"
+	CodeUnsupportedAvatar = "unsupported_avatar"
+
+	CodeMemberNotFound   = "member_not_found"
+	CodeAlreadyMember    = "already_member"
+	CodeInsufficientRole = "insufficient_role"
+	CodeNotGroupChat     = "not_group_chat"
+	CodeOwnerCannotLeave = "owner_cannot_leave"
+	CodeNotChannel       = "not_channel"
+
+	CodeInviteNotFound = "invite_not_found"
+	CodeInviteInvalid  = "invite_invalid"
+
+	CodeScheduledNotFound = "scheduled_message_not_found"
+)
+
+// Error is an API error as rendered to clients.
+type Error struct {
+	Status int
+	Code   string
+	Detail interface{}
+	// Reason further explains some codes, such as why a message was rejected.
+	Reason string
+	Fields []validator.FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Code
+}
+
+// StatusCode returns the default error code for an HTTP status.
+func StatusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	default:
+		return CodeInternal
+	}
+}
+
+// ValidationErrorResponse reports a failed request validation as 422 with
+// the individual field errors, translated according to Accept-Language.
+func ValidationErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var fields validator.Errors
+	if !errors.As(err, &fields) {
+		CodedErrorResponse(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+		return
+	}
+
+	trans := validator.TranslatorFor(r.Header.Get("Accept-Language"))
+	fields = trans.Errors(fields)
+
+	RenderError(w, r, &Error{
+		Status: http.StatusUnprocessableEntity,
+		Code:   CodeValidationFailed,
+		Detail: trans.Summary(fields),
+		Fields: fields,
+	})
+}
+
+// serviceErrors maps sentinel errors returned by services and storage to
+// API errors.
+var serviceErrors = []struct {
+	target error
+	status int
+	code   string
+	detail string
+}{
+	{chatStorage.ErrChatNotFound, http.StatusNotFound, CodeChatNotFound, "chat not found"},
+	{messageStorage.ErrMessageNotFound, http.StatusNotFound, CodeMessageNotFound, "message not found"},
+	{reportStorage.ErrReportNotFound, http.StatusNotFound, CodeReportNotFound, "report not found"},
+	{userStorage.ErrNotSuspended, http.StatusNotFound, CodeSuspensionNotFound, "user is not suspended"},
+	{chatService.ErrNotChatMember, http.StatusForbidden, CodeNotChatMember, "you are not a member of this chat"},
+	{messageService.ErrSenderSuspended, http.StatusForbidden, CodeUserSuspended, "you are suspended from sending messages"},
+	{chatService.ErrBlocked, http.StatusForbidden, CodeUserBlocked, "you were blocked by this user"},
+	{userStorage.ErrNotBlocked, http.StatusNotFound, CodeBlockNotFound, "user is not blocked"},
+	{userService.ErrBlockSelf, http.StatusUnprocessableEntity, CodeCannotBlockSelf, "you cannot block yourself"},
+	{chatStorage.ErrMemberNotFound, http.StatusNotFound, CodeMemberNotFound, "user is not a member of this chat"},
+	{chatStorage.ErrAlreadyMember, http.StatusConflict, CodeAlreadyMember, "user is already a member of this chat"},
+	{chatService.ErrNotAllowed, http.StatusForbidden, CodeInsufficientRole, "your role in this chat does not allow this"},
+	{chatService.ErrNotGroupChat, http.StatusConflict, CodeNotGroupChat, "chat is not a group chat"},
+	{chatService.ErrNotChannel, http.StatusConflict, CodeNotChannel, "chat is not a channel"},
+	{chatService.ErrOwnerCannotLeave, http.StatusConflict, CodeOwnerCannotLeave, "transfer ownership before leaving the chat"},
+	{inviteStorage.ErrInviteNotFound, http.StatusNotFound, CodeInviteNotFound, "invite not found"},
+	{inviteStorage.ErrInviteInvalid, http.StatusNotFound, CodeInviteInvalid, "invite link is invalid or expired"},
+	{scheduledStorage.ErrScheduledNotFound, http.StatusNotFound, CodeScheduledNotFound, "scheduled message not found"},
+	{avatar.ErrTooLarge, http.StatusRequestEntityTooLarge, CodeAvatarTooLarge, "avatar is too large"},
+	{avatar.ErrUnsupportedFormat, http.StatusUnsupportedMediaType, CodeUnsupportedAvatar, "avatar must be a png, jpeg, gif or webp image"},
+}
+
+// ServiceError maps an error returned by a service to an API error. Known
+// sentinels get their own status and code, validation failures are 422 and
+// messages rejected by moderation are 422 with the reason; anything else is
+// an internal error with fallback as the detail.
+func ServiceError(err error, fallback string) *Error {
+	var (
+		fields   validator.Errors
+		rejected *moderation.RejectedError
+	)
+
+	if errors.As(err, &rejected) {
+		return &Error{
+			Status: http.StatusUnprocessableEntity,
+			Code:   CodeMessageRejected,
+			Detail: "message rejected",
+			Reason: rejected.Reason,
+		}
+	}
+
+	for _, known := range serviceErrors {
+		if errors.Is(err, known.target) {
+			return &Error{Status: known.status, Code: known.code, Detail: known.detail}
+		}
+	}
+
+	if errors.As(err, &fields) {
+		return &Error{
+			Status: http.StatusUnprocessableEntity,
+			Code:   CodeValidationFailed,
+			Detail: err.Error(),
+			Fields: fields,
+		}
+	}
+
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Detail: fallback}
+}
+
+// ServiceErrorResponse reports an error returned by a service as mapped by
+// ServiceError.
+func ServiceErrorResponse(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	apiErr := ServiceError(err, fallback)
+	if apiErr.Fields != nil {
+		ValidationErrorResponse(w, r, err)
+		return
+	}
+	RenderError(w, r, apiErr)
+}
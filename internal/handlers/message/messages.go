@@ -5,12 +5,13 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
-	ssogrpc "simple-chat/internal/clients/sso/grpc"
 	"simple-chat/internal/domain/dto"
 	"simple-chat/internal/domain/models"
 	"simple-chat/internal/handlers"
 	"simple-chat/internal/lib/logger/sl"
 	authMiddleware "simple-chat/internal/lib/middleware"
+	"simple-chat/internal/lib/moderation"
+	"simple-chat/internal/lib/ratelimit"
 	"strconv"
 	"time"
 
@@ -21,30 +22,52 @@ import (
 type MessageHandler struct {
 	log            *slog.Logger
 	messageService MessageService
+	users          UserProvider
+	limiter        MessageLimiter
 	appID          int32
 }
 
 type MessageService interface {
-	CreateMessage(ctx context.Context, message dto.Message) (int64, error)
+	CheckSender(ctx context.Context, sender int64, chatID int64) error
+	CheckMember(ctx context.Context, userID int64, chatID int64) error
+	SendMessage(ctx context.Context, message dto.Message) (models.Message, moderation.Decision, error)
 	GetMessagesByChatID(ctx context.Context, chatID int64, limit int, offset int) ([]models.Message, error)
+	ScheduleMessage(ctx context.Context, message dto.Message, sendAt time.Time) (models.ScheduledMessage, error)
+	GetScheduledMessages(ctx context.Context, sender int64) ([]models.ScheduledMessage, error)
+	UpdateScheduledMessage(ctx context.Context, sender int64, messageID int64, req dto.UpdateScheduledRequest) (models.ScheduledMessage, error)
+	CancelScheduledMessage(ctx context.Context, sender int64, messageID int64) error
 }
 
-func NewMessageHandler(log *slog.Logger, messageService MessageService, appID int32) *MessageHandler {
+type MessageLimiter interface {
+	AllowMessage(ctx context.Context, userID int64, chatID int64, connID string) (ratelimit.Result, error)
+}
+
+type UserProvider interface {
+	GetUsers(ctx context.Context, appID int32, userIDs []int64) (map[int64]models.User, error)
+}
+
+func NewMessageHandler(log *slog.Logger, messageService MessageService, users UserProvider, limiter MessageLimiter, appID int32) *MessageHandler {
 	return &MessageHandler{
 		log:            log,
 		messageService: messageService,
+		users:          users,
+		limiter:        limiter,
 		appID:          appID,
 	}
 }
 
-func AddMessageHandler(log *slog.Logger, messageService MessageService, ssoClient *ssogrpc.Client, appID int32) func(chi.Router) {
-	messageHandler := NewMessageHandler(log, messageService, appID)
+func AddMessageHandler(log *slog.Logger, messageService MessageService, users UserProvider, limiter MessageLimiter, verifier authMiddleware.TokenVerifier, appID int32) func(chi.Router) {
+	messageHandler := NewMessageHandler(log, messageService, users, limiter, appID)
 
 	return func(r chi.Router) {
-		r.Use(authMiddleware.Auth(log, ssoClient, appID))
+		r.Use(authMiddleware.Auth(log, verifier, appID))
 
 		r.Post("/create", messageHandler.Create(context.Background()))
 		r.Get("/{chat_id}", messageHandler.GetMessagesByChatID(context.Background()))
+		r.Get("/scheduled", messageHandler.GetScheduled(context.Background()))
+		r.Post("/scheduled", messageHandler.Schedule(context.Background()))
+		r.Patch("/scheduled/{scheduled_id}", messageHandler.UpdateScheduled(context.Background()))
+		r.Delete("/scheduled/{scheduled_id}", messageHandler.CancelScheduled(context.Background()))
 	}
 }
 
@@ -66,7 +89,7 @@ func (h *MessageHandler) Create(ctx context.Context) http.HandlerFunc {
 		}
 		if err := message.Validate(); err != nil {
 			h.log.Error("failed to validate message", sl.Err(err))
-			handlers.ErrorResponse(w, r, 422, err.Error())
+			handlers.ValidationErrorResponse(w, r, err)
 			return
 		}
 
@@ -77,6 +100,22 @@ func (h *MessageHandler) Create(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		// Only senders allowed in the chat are charged against its limit.
+		if err := h.messageService.CheckSender(ctx, user.UserID, message.ChatID); err != nil {
+			h.log.Error("user may not send to chat", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to create message")
+			return
+		}
+
+		limit, err := h.limiter.AllowMessage(ctx, user.UserID, message.ChatID, "")
+		if err != nil {
+			h.log.Error("failed to check message rate limit", sl.Err(err))
+		} else if !limit.Allowed {
+			h.log.Error("message rate limit exceeded", slog.Int64("user_id", user.UserID))
+			handlers.TooManyRequestsResponse(w, r, limit.RetryAfter, "too many messages")
+			return
+		}
+
 		messageModel := dto.Message{
 			ChatID:    message.ChatID,
 			Sender:    user.UserID,
@@ -84,23 +123,21 @@ func (h *MessageHandler) Create(ctx context.Context) http.HandlerFunc {
 			CreatedAt: time.Now().UTC(),
 		}
 
-		if err := message.Validate(); err != nil {
-			h.log.Error("failed to validate message", sl.Err(err))
-			handlers.ErrorResponse(w, r, 422, err.Error())
-			return
-		}
-
-		messageID, err := h.messageService.CreateMessage(ctx, messageModel)
+		sent, decision, err := h.messageService.SendMessage(ctx, messageModel)
 		if err != nil {
 			h.log.Error("failed to create message", sl.Err(err))
-			handlers.ErrorResponse(w, r, 500, "failed to create message")
+			handlers.ServiceErrorResponse(w, r, err, "failed to create message")
 			return
 		}
 
-		handlers.SuccessResponse(w, r, 200, map[string]any{
+		resp := map[string]any{
 			"message":    "message successfully created",
-			"message_id": messageID,
-		})
+			"message_id": sent.ID,
+		}
+		if decision.Action != moderation.Allow {
+			resp["moderation"] = decision
+		}
+		handlers.SuccessResponse(w, r, 200, resp)
 	}
 }
 
@@ -130,10 +167,23 @@ func (h *MessageHandler) GetMessagesByChatID(ctx context.Context) http.HandlerFu
 			return
 		}
 
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		if err := h.messageService.CheckMember(ctx, user.UserID, chatID); err != nil {
+			h.log.Error("user is not a chat member", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to check chat membership")
+			return
+		}
+
 		messages, err := h.messageService.GetMessagesByChatID(ctx, chatID, limit, offset)
 		if err != nil {
 			h.log.Error("failed to get messages by chat id", sl.Err(err))
-			handlers.ErrorResponse(w, r, 500, "failed to get messages")
+			handlers.ServiceErrorResponse(w, r, err, "failed to get messages")
 			return
 		}
 		if messages == nil {
@@ -142,6 +192,35 @@ func (h *MessageHandler) GetMessagesByChatID(ctx context.Context) http.HandlerFu
 			return
 		}
 
+		if handlers.Expand(r, handlers.ExpandParticipants) {
+			if err := h.attachSenders(ctx, messages); err != nil {
+				h.log.Error("failed to get message senders", sl.Err(err))
+				handlers.ErrorResponse(w, r, 503, "failed to get message senders")
+				return
+			}
+		}
+
 		handlers.SuccessResponse(w, r, 200, messages)
 	}
 }
+
+func (h *MessageHandler) attachSenders(ctx context.Context, messages []models.Message) error {
+	userIDs := make([]int64, 0, len(messages))
+	for _, message := range messages {
+		userIDs = append(userIDs, message.Sender)
+	}
+
+	users, err := h.users.GetUsers(ctx, h.appID, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for idx := range messages {
+		sender, ok := users[messages[idx].Sender]
+		if !ok {
+			sender = models.User{UserID: messages[idx].Sender}
+		}
+		messages[idx].SenderProfile = &sender
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/logger/sl"
+	authMiddleware "simple-chat/internal/lib/middleware"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func (h *MessageHandler) Schedule(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.message.Schedule"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req dto.ScheduleMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.log.Error("failed to decode request body", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		scheduled, err := h.messageService.ScheduleMessage(ctx, dto.Message{
+			ChatID:    req.ChatID,
+			Sender:    user.UserID,
+			Text:      req.Text,
+			CreatedAt: time.Now().UTC(),
+		}, req.SendAt.UTC())
+		if err != nil {
+			h.log.Error("failed to schedule message", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to schedule message")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 201, scheduled)
+	}
+}
+
+func (h *MessageHandler) GetScheduled(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.message.GetScheduled"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		messages, err := h.messageService.GetScheduledMessages(ctx, user.UserID)
+		if err != nil {
+			h.log.Error("failed to get scheduled messages", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to get scheduled messages")
+			return
+		}
+		if messages == nil {
+			messages = []models.ScheduledMessage{}
+		}
+
+		handlers.SuccessResponse(w, r, 200, messages)
+	}
+}
+
+func (h *MessageHandler) UpdateScheduled(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.message.UpdateScheduled"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		scheduledID, err := strconv.ParseInt(chi.URLParam(r, "scheduled_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse scheduled_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		var req dto.UpdateScheduledRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.log.Error("failed to decode request body", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+		if req.SendAt != nil {
+			sendAt := req.SendAt.UTC()
+			req.SendAt = &sendAt
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		scheduled, err := h.messageService.UpdateScheduledMessage(ctx, user.UserID, scheduledID, req)
+		if err != nil {
+			h.log.Error("failed to update scheduled message", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to update scheduled message")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 200, scheduled)
+	}
+}
+
+func (h *MessageHandler) CancelScheduled(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.message.CancelScheduled"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		scheduledID, err := strconv.ParseInt(chi.URLParam(r, "scheduled_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse scheduled_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		if err := h.messageService.CancelScheduledMessage(ctx, user.UserID, scheduledID); err != nil {
+			h.log.Error("failed to cancel scheduled message", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to cancel scheduled message")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message":      "scheduled message successfully cancelled",
+			"scheduled_id": scheduledID,
+		})
+	}
+}
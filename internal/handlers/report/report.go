@@ -0,0 +1,84 @@
+package report
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/logger/sl"
+	authMiddleware "simple-chat/internal/lib/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type ReportHandler struct {
+	log           *slog.Logger
+	reportService ReportService
+}
+
+type ReportService interface {
+	CreateReport(ctx context.Context, reporter int64, req dto.ReportRequest) (int64, error)
+}
+
+func NewReportHandler(log *slog.Logger, reportService ReportService) *ReportHandler {
+	return &ReportHandler{
+		log:           log,
+		reportService: reportService,
+	}
+}
+
+func AddReportHandler(log *slog.Logger, reportService ReportService, verifier authMiddleware.TokenVerifier, appID int32) func(r chi.Router) {
+	reportHandler := NewReportHandler(log, reportService)
+
+	return func(r chi.Router) {
+		r.Use(authMiddleware.Auth(log, verifier, appID))
+
+		r.Post("/create", reportHandler.CreateReport(context.Background()))
+	}
+}
+
+func (h *ReportHandler) CreateReport(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.report.CreateReport"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req dto.ReportRequest
+		if err := render.Decode(r, &req); err != nil {
+			h.log.Error("failed to decode request", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.log.Error("failed to validate request", sl.Err(err))
+			handlers.ValidationErrorResponse(w, r, err)
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		reportID, err := h.reportService.CreateReport(ctx, user.UserID, req)
+		if err != nil {
+			h.log.Error("failed to create report", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to create report")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 201, map[string]any{
+			"message":   "report successfully created",
+			"report_id": reportID,
+		})
+	}
+}
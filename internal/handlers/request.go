@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+const ExpandParticipants = "participants"
+
+// Expand reports whether the comma separated "expand" query parameter
+// contains the given field.
+func Expand(r *http.Request, field string) bool {
+	for _, value := range strings.Split(r.URL.Query().Get("expand"), ",") {
+		if strings.TrimSpace(value) == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client address without the port. RemoteAddr has
+// already been replaced by middleware.RealIP when the request came through a
+// trusted proxy.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
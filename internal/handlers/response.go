@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"math"
 	"net/http"
+	"simple-chat/internal/validator"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 )
 
@@ -12,16 +17,48 @@ const (
 )
 
 type Response struct {
-	Status string      `json:"status"`
-	Detail interface{} `json:"detail"`
+	Status    string                 `json:"status"`
+	Code      string                 `json:"code,omitempty"`
+	Detail    interface{}            `json:"detail"`
+	Reason    string                 `json:"reason,omitempty"`
+	Errors    []validator.FieldError `json:"errors,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
 }
 
+// ErrorResponse reports an error with the default code for its HTTP status.
 func ErrorResponse(w http.ResponseWriter, r *http.Request, status int, detail interface{}) {
-	render.Status(r, status)
-	render.JSON(w, r, Response{Status: StatusError, Detail: detail})
+	CodedErrorResponse(w, r, status, StatusCode(status), detail)
+}
+
+// CodedErrorResponse is ErrorResponse with a stable error code.
+func CodedErrorResponse(w http.ResponseWriter, r *http.Request, status int, code string, detail interface{}) {
+	RenderError(w, r, &Error{Status: status, Code: code, Detail: detail})
+}
+
+// RenderError writes a typed API error together with the request ID.
+func RenderError(w http.ResponseWriter, r *http.Request, err *Error) {
+	render.Status(r, err.Status)
+	render.JSON(w, r, Response{
+		Status:    StatusError,
+		Code:      err.Code,
+		Detail:    err.Detail,
+		Reason:    err.Reason,
+		Errors:    err.Fields,
+		RequestID: middleware.GetReqID(r.Context()),
+	})
 }
 
 func SuccessResponse(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
 	render.Status(r, status)
 	render.JSON(w, r, data)
 }
+
+// RetryAfterSeconds rounds a wait duration up to whole seconds, at least one.
+func RetryAfterSeconds(d time.Duration) int {
+	return max(int(math.Ceil(d.Seconds())), 1)
+}
+
+func TooManyRequestsResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration, detail interface{}) {
+	w.Header().Set("Retry-After", strconv.Itoa(RetryAfterSeconds(retryAfter)))
+	CodedErrorResponse(w, r, http.StatusTooManyRequests, CodeRateLimited, detail)
+}
@@ -0,0 +1,147 @@
+package user
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/logger/sl"
+	authMiddleware "simple-chat/internal/lib/middleware"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type UserHandler struct {
+	log         *slog.Logger
+	userService UserService
+}
+
+type UserService interface {
+	BlockUser(ctx context.Context, blocker int64, blocked int64) error
+	UnblockUser(ctx context.Context, blocker int64, blocked int64) error
+	GetBlockedUsers(ctx context.Context, blocker int64) ([]models.Block, error)
+}
+
+func NewUserHandler(log *slog.Logger, userService UserService) *UserHandler {
+	return &UserHandler{
+		log:         log,
+		userService: userService,
+	}
+}
+
+func AddUserHandler(log *slog.Logger, userService UserService, verifier authMiddleware.TokenVerifier, appID int32) func(r chi.Router) {
+	userHandler := NewUserHandler(log, userService)
+
+	return func(r chi.Router) {
+		r.Use(authMiddleware.Auth(log, verifier, appID))
+
+		r.Get("/blocks", userHandler.GetBlockedUsers(context.Background()))
+		r.Post("/block/{user_id}", userHandler.BlockUser(context.Background()))
+		r.Delete("/block/{user_id}", userHandler.UnblockUser(context.Background()))
+	}
+}
+
+func (h *UserHandler) BlockUser(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.user.BlockUser"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse user_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		if err := h.userService.BlockUser(ctx, user.UserID, userID); err != nil {
+			h.log.Error("failed to block user", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to block user")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message": "user successfully blocked",
+			"user_id": userID,
+		})
+	}
+}
+
+func (h *UserHandler) UnblockUser(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.user.UnblockUser"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+		if err != nil {
+			h.log.Error("failed to parse user_id", sl.Err(err))
+			handlers.ErrorResponse(w, r, 400, "bad request")
+			return
+		}
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		if err := h.userService.UnblockUser(ctx, user.UserID, userID); err != nil {
+			h.log.Error("failed to unblock user", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to unblock user")
+			return
+		}
+
+		handlers.SuccessResponse(w, r, 200, map[string]any{
+			"message": "user successfully unblocked",
+			"user_id": userID,
+		})
+	}
+}
+
+func (h *UserHandler) GetBlockedUsers(ctx context.Context) http.HandlerFunc {
+	const op = "handlers.user.GetBlockedUsers"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.log = h.log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, ok := r.Context().Value(authMiddleware.UserContextKey).(models.User)
+		if !ok {
+			h.log.Error("failed to get user")
+			handlers.ErrorResponse(w, r, 401, "unauthorized")
+			return
+		}
+
+		blocks, err := h.userService.GetBlockedUsers(ctx, user.UserID)
+		if err != nil {
+			h.log.Error("failed to get blocked users", sl.Err(err))
+			handlers.ServiceErrorResponse(w, r, err, "failed to get blocked users")
+			return
+		}
+		if blocks == nil {
+			blocks = []models.Block{}
+		}
+
+		handlers.SuccessResponse(w, r, 200, blocks)
+	}
+}
@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"context"
+	"simple-chat/internal/config"
+	"simple-chat/internal/lib/cache"
+)
+
+// maxCachedUsers bounds the number of remembered SSO answers.
+const maxCachedUsers = 10000
+
+// SSOChecker asks SSO whether a user is an administrator of the app.
+type SSOChecker interface {
+	IsAdmin(ctx context.Context, appID int32, userID int64) (bool, error)
+}
+
+// Checker decides who may use the admin endpoints: users listed in the
+// config, and, when an SSO checker is given, users SSO reports as admins.
+type Checker struct {
+	userIDs map[int64]struct{}
+	sso     SSOChecker
+	appID   int32
+	answers *cache.Cache[int64, bool]
+}
+
+// NewChecker builds a checker; sso may be nil to rely on the configured IDs
+// only.
+func NewChecker(cfg config.Admin, sso SSOChecker, appID int32) *Checker {
+	userIDs := make(map[int64]struct{}, len(cfg.UserIDs))
+	for _, userID := range cfg.UserIDs {
+		userIDs[userID] = struct{}{}
+	}
+
+	return &Checker{
+		userIDs: userIDs,
+		sso:     sso,
+		appID:   appID,
+		answers: cache.New[int64, bool](cfg.CacheTTL, maxCachedUsers),
+	}
+}
+
+func (c *Checker) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	if _, ok := c.userIDs[userID]; ok {
+		return true, nil
+	}
+	if c.sso == nil {
+		return false, nil
+	}
+
+	if isAdmin, ok := c.answers.Get(userID); ok {
+		return isAdmin, nil
+	}
+
+	isAdmin, err := c.sso.IsAdmin(ctx, c.appID, userID)
+	if err != nil {
+		return false, err
+	}
+	c.answers.Set(userID, isAdmin)
+
+	return isAdmin, nil
+}
@@ -0,0 +1,102 @@
+package avatar
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"simple-chat/internal/config"
+)
+
+var (
+	ErrTooLarge          = errors.New("avatar is too large")
+	ErrUnsupportedFormat = errors.New("avatar must be a png, jpeg, gif or webp image")
+	ErrNotFound          = errors.New("avatar not found")
+)
+
+var (
+	formats     = map[string]bool{"image/png": true, "image/jpeg": true, "image/gif": true, "image/webp": true}
+	namePattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// Store keeps uploaded chat avatars on disk under random names. Names have
+// no extension; the content type is sniffed when they are served.
+type Store struct {
+	dir     string
+	maxSize int64
+}
+
+func NewStore(cfg config.Avatars) (*Store, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		dir:     cfg.Dir,
+		maxSize: cfg.MaxSize,
+	}, nil
+}
+
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Save stores an image read from r and returns its file name. The format is
+// detected from the content, not from what the client claims.
+func (s *Store) Save(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > s.maxSize {
+		return "", ErrTooLarge
+	}
+
+	if !formats[http.DetectContentType(data)] {
+		return "", ErrUnsupportedFormat
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(buf)
+
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0o644); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// Path returns the location of a stored avatar, rejecting names Save could
+// not have produced.
+func (s *Store) Path(name string) (string, error) {
+	if !namePattern.MatchString(name) {
+		return "", ErrNotFound
+	}
+
+	path := filepath.Join(s.dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrNotFound
+	}
+
+	return path, nil
+}
+
+// Remove deletes a stored avatar; unknown names are ignored.
+func (s *Store) Remove(name string) error {
+	if !namePattern.MatchString(name) {
+		return nil
+	}
+
+	err := os.Remove(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
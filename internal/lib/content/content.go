@@ -0,0 +1,102 @@
+package content
+
+import (
+	"fmt"
+	"html"
+	"simple-chat/internal/config"
+	"simple-chat/internal/validator"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Step transforms message text. Steps run in order and the first error stops
+// the pipeline.
+type Step func(text string) (string, error)
+
+// Pipeline prepares user supplied message text for storage.
+type Pipeline struct {
+	steps []Step
+}
+
+// NewPipeline builds the pipeline described by cfg: NFC normalization,
+// control and invisible character stripping, trimming, the length check and
+// finally HTML escaping, so the limit applies to the text the user typed.
+func NewPipeline(cfg config.MessageContent) *Pipeline {
+	var steps []Step
+
+	if cfg.Normalize {
+		steps = append(steps, Normalize)
+	}
+	if cfg.StripControl {
+		steps = append(steps, StripControl)
+	}
+	steps = append(steps, Trim, Limit(cfg.MaxLength))
+	if cfg.EscapeHTML {
+		steps = append(steps, EscapeHTML)
+	}
+
+	return &Pipeline{steps: steps}
+}
+
+// Process runs text through every step.
+func (p *Pipeline) Process(text string) (string, error) {
+	var err error
+	for _, step := range p.steps {
+		if text, err = step(text); err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
+
+// Normalize converts text to Unicode normalization form C, so visually equal
+// messages are stored identically.
+func Normalize(text string) (string, error) {
+	return norm.NFC.String(text), nil
+}
+
+// StripControl removes control characters other than newlines and tabs, and
+// invisible formatting characters such as zero-width spaces, byte order marks
+// and bidirectional overrides. Zero-width joiners and non-joiners are kept
+// because emoji sequences and several scripts depend on them.
+func StripControl(text string) (string, error) {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\u200c' || r == '\u200d':
+			return r
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			return -1
+		default:
+			return r
+		}
+	}, text), nil
+}
+
+func Trim(text string) (string, error) {
+	return strings.TrimSpace(text), nil
+}
+
+// Limit rejects empty text and text longer than maxLength characters. A
+// maxLength of zero only rejects empty text.
+func Limit(maxLength int) Step {
+	tag := "required"
+	if maxLength > 0 {
+		tag = fmt.Sprintf("required,max=%d", maxLength)
+	}
+
+	return func(text string) (string, error) {
+		if err := validator.Var("text", text, tag); err != nil {
+			return "", err
+		}
+		return text, nil
+	}
+}
+
+// EscapeHTML escapes markup for clients that render messages as HTML.
+func EscapeHTML(text string) (string, error) {
+	return html.EscapeString(text), nil
+}
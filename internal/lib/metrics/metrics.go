@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "simple_chat"
+
+const (
+	ResultHit         = "hit"
+	ResultNegativeHit = "negative_hit"
+	ResultMiss        = "miss"
+)
+
+var TokenCacheRequests = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sso",
+		Name:      "token_cache_requests_total",
+		Help:      "Token validations served by the SSO token cache, by result.",
+	},
+	[]string{"result"},
+)
+
+var ModerationDecisions = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "moderation",
+		Name:      "decisions_total",
+		Help:      "Moderation decisions on sent messages, by action and reason.",
+	},
+	[]string{"action", "reason"},
+)
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/handlers"
+	"simple-chat/internal/lib/logger/sl"
+)
+
+// AdminChecker decides whether a user may use the admin endpoints.
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID int64) (bool, error)
+}
+
+// RequireAdmin lets only admins through. It must run after Auth.
+func RequireAdmin(log *slog.Logger, checker AdminChecker) func(next http.Handler) http.Handler {
+	const op = "middleware.admin.RequireAdmin"
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value(UserContextKey).(models.User)
+			if !ok {
+				log.Error("unauthorized", slog.String("op", op))
+				handlers.ErrorResponse(w, r, 401, "unauthorized")
+				return
+			}
+
+			isAdmin, err := checker.IsAdmin(r.Context(), user.UserID)
+			if err != nil {
+				log.Error("failed to check admin status", sl.OpErr(op, err))
+				handlers.ErrorResponse(w, r, 503, "failed to check admin status")
+				return
+			}
+			if !isAdmin {
+				log.Warn("admin endpoint denied", slog.String("op", op), slog.Int64("user_id", user.UserID))
+				handlers.ErrorResponse(w, r, 403, "admin access required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,16 +2,15 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	ssogrpc "simple-chat/internal/clients/sso/grpc"
+	"simple-chat/internal/clients/sso"
 	"simple-chat/internal/domain/models"
 	"simple-chat/internal/handlers"
 	"simple-chat/internal/lib/logger/sl"
 	"strings"
-
-	ssov1 "github.com/bordviz/sso-protos/gen/go/sso"
 )
 
 type ContextKey struct {
@@ -20,7 +19,12 @@ type ContextKey struct {
 
 var UserContextKey = &ContextKey{"user"}
 
-func Auth(log *slog.Logger, client *ssogrpc.Client, appID int32) func(next http.Handler) http.Handler {
+// TokenVerifier resolves the user that owns an access token.
+type TokenVerifier interface {
+	CurrentUser(ctx context.Context, token string, appID int32) (models.User, error)
+}
+
+func Auth(log *slog.Logger, verifier TokenVerifier, appID int32) func(next http.Handler) http.Handler {
 	const op = "middleware.auth.Auth"
 
 	return func(next http.Handler) http.Handler {
@@ -33,29 +37,21 @@ func Auth(log *slog.Logger, client *ssogrpc.Client, appID int32) func(next http.
 				}
 			}()
 
-			token := strings.ReplaceAll(r.Header.Get("Authorization"), "Bearer ", "")
-			user, err := client.Api.CurrentUser(
-				context.Background(),
-				&ssov1.CurrentUserRequest{
-					Token: token,
-					AppId: appID,
-				},
-			)
+			token := BearerToken(r)
+			userModel, err := verifier.CurrentUser(context.Background(), token, appID)
 			if err != nil {
+				if errors.Is(err, sso.ErrUnavailable) {
+					log.Error("failed to verify token", sl.OpErr(op, err))
+					handlers.CodedErrorResponse(w, r, 503, handlers.CodeAuthUnavailable, "authentication service is unavailable")
+					return
+				}
 				log.Error("unauthorized", sl.OpErr(op, err))
-				handlers.ErrorResponse(w, r, 401, "unauthorized")
+				handlers.CodedErrorResponse(w, r, 401, handlers.CodeInvalidToken, "unauthorized")
 				return
 			}
 
-			userModel := models.User{
-				UserID: user.GetUserId(),
-				Email:  user.GetEmail(),
-				Name:   user.GetName(),
-			}
-
-			log.Debug("user from sso",
+			log.Debug("user from token",
 				slog.String("op", op),
-				slog.Any("user", user),
 				slog.Any("user_model", userModel),
 			)
 
@@ -65,3 +61,8 @@ func Auth(log *slog.Logger, client *ssogrpc.Client, appID int32) func(next http.
 		})
 	}
 }
+
+// BearerToken returns the access token from the Authorization header.
+func BearerToken(r *http.Request) string {
+	return strings.ReplaceAll(r.Header.Get("Authorization"), "Bearer ", "")
+}
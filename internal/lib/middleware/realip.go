@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP replaces RemoteAddr with the client address from X-Forwarded-For or
+// X-Real-IP. The headers are honoured only when the request comes from one of
+// the trusted proxies, given as addresses or CIDR ranges; anyone else could
+// rotate them to dodge per-IP limits. With no trusted proxies RemoteAddr is
+// left untouched.
+func RealIP(trusted []string) (func(next http.Handler) http.Handler, error) {
+	prefixes := make([]netip.Prefix, 0, len(trusted))
+	for _, value := range trusted {
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if len(prefixes) > 0 {
+				if peer, ok := remoteAddr(r.RemoteAddr); ok && isTrusted(peer) {
+					if ip, ok := forwardedFor(r, isTrusted); ok {
+						r.RemoteAddr = ip.String()
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}, nil
+}
+
+// forwardedFor walks X-Forwarded-For from the nearest hop and returns the
+// first address that is not a trusted proxy, falling back to X-Real-IP.
+func forwardedFor(r *http.Request, isTrusted func(netip.Addr) bool) (netip.Addr, bool) {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+
+		var last netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			last = addr
+			if !isTrusted(addr) {
+				return addr, true
+			}
+		}
+		if last.IsValid() {
+			return last, true
+		}
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr, true
+	}
+
+	return netip.Addr{}, false
+}
+
+func remoteAddr(value string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(value)
+	if err != nil {
+		host = value
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr, true
+}
+
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,124 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"simple-chat/internal/config"
+)
+
+// Action is what happens to a moderated message.
+type Action string
+
+const (
+	// Allow stores the message unchanged.
+	Allow Action = "allow"
+	// Mask stores the message with the offending parts replaced.
+	Mask Action = "mask"
+	// Flag stores the message and marks it for review.
+	Flag Action = "flag"
+	// Reject refuses to store the message.
+	Reject Action = "reject"
+)
+
+// severity orders actions so a chain reports the strictest one.
+var severity = map[Action]int{
+	Allow:  0,
+	Mask:   1,
+	Flag:   2,
+	Reject: 3,
+}
+
+func ParseAction(s string) (Action, error) {
+	action := Action(s)
+	if _, ok := severity[action]; !ok || action == Allow {
+		return "", fmt.Errorf("unknown moderation action %q", s)
+	}
+	return action, nil
+}
+
+// Reasons are stable identifiers reported to the sender.
+const (
+	ReasonProfanity       = "profanity"
+	ReasonRepeatedContent = "repeated_content"
+	ReasonCharacterFlood  = "character_flood"
+)
+
+// Message is the content being moderated.
+type Message struct {
+	ChatID int64
+	Sender int64
+	Text   string
+}
+
+// Decision is the outcome of moderation. Text is the text to store, which
+// differs from the original when it was masked.
+type Decision struct {
+	Action Action `json:"action"`
+	Reason string `json:"reason,omitempty"`
+	Text   string `json:"-"`
+}
+
+// Moderator screens a message before it is stored.
+type Moderator interface {
+	Moderate(ctx context.Context, msg Message) (Decision, error)
+}
+
+// RejectedError is returned by the send path for rejected messages.
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return "message rejected: " + e.Reason
+}
+
+// Chain runs moderators in order. Masks are applied cumulatively, the first
+// rejection stops the chain, and the strictest action is reported.
+type Chain []Moderator
+
+func (c Chain) Moderate(ctx context.Context, msg Message) (Decision, error) {
+	result := Decision{Action: Allow, Text: msg.Text}
+
+	for _, moderator := range c {
+		decision, err := moderator.Moderate(ctx, msg)
+		if err != nil {
+			return Decision{}, err
+		}
+
+		switch decision.Action {
+		case Reject:
+			decision.Text = ""
+			return decision, nil
+		case Mask:
+			msg.Text = decision.Text
+			result.Text = decision.Text
+		}
+
+		if severity[decision.Action] > severity[result.Action] {
+			result.Action = decision.Action
+			result.Reason = decision.Reason
+		}
+	}
+
+	return result, nil
+}
+
+// New builds the configured moderators. With moderation disabled the chain
+// is empty and allows everything.
+func New(cfg config.Moderation) (Chain, error) {
+	if !cfg.Enabled {
+		return Chain{}, nil
+	}
+
+	words, err := NewWordFilter(cfg.WordFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	spam, err := NewSpamFilter(cfg.Spam)
+	if err != nil {
+		return nil, err
+	}
+
+	return Chain{words, spam}, nil
+}
@@ -0,0 +1,105 @@
+package moderation
+
+import (
+	"context"
+	"hash/fnv"
+	"simple-chat/internal/config"
+	"simple-chat/internal/lib/cache"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTrackedSenders bounds the memory used for message history.
+const maxTrackedSenders = 100000
+
+// SpamFilter catches senders repeating the same message within a window and
+// messages that are mostly one character repeated.
+type SpamFilter struct {
+	action Action
+	cfg    config.Spam
+
+	mu      sync.Mutex
+	history *cache.Cache[int64, []sighting]
+}
+
+type sighting struct {
+	fingerprint uint64
+	at          time.Time
+}
+
+func NewSpamFilter(cfg config.Spam) (*SpamFilter, error) {
+	action, err := ParseAction(cfg.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpamFilter{
+		action:  action,
+		cfg:     cfg,
+		history: cache.New[int64, []sighting](cfg.Window, maxTrackedSenders),
+	}, nil
+}
+
+func (f *SpamFilter) Moderate(_ context.Context, msg Message) (Decision, error) {
+	if f.cfg.MaxCharRun > 0 && longestRun(msg.Text) > f.cfg.MaxCharRun {
+		return Decision{Action: f.action, Reason: ReasonCharacterFlood, Text: msg.Text}, nil
+	}
+
+	if f.cfg.MaxRepeats > 0 && f.repeats(msg) > f.cfg.MaxRepeats {
+		return Decision{Action: f.action, Reason: ReasonRepeatedContent, Text: msg.Text}, nil
+	}
+
+	return Decision{Action: Allow, Text: msg.Text}, nil
+}
+
+// repeats records the message and returns how many times the sender sent the
+// same content, in any chat, within the window.
+func (f *SpamFilter) repeats(msg Message) int {
+	now := time.Now()
+	fingerprint := fingerprint(msg.Text)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	previous, _ := f.history.Get(msg.Sender)
+	recent := make([]sighting, 0, len(previous)+1)
+	count := 1
+	for _, s := range previous {
+		if now.Sub(s.at) > f.cfg.Window {
+			continue
+		}
+		recent = append(recent, s)
+		if s.fingerprint == fingerprint {
+			count++
+		}
+	}
+	recent = append(recent, sighting{fingerprint: fingerprint, at: now})
+	f.history.Set(msg.Sender, recent)
+
+	return count
+}
+
+// fingerprint ignores case and whitespace differences.
+func fingerprint(text string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(strings.Fields(strings.ToLower(text)), " ")))
+	return h.Sum64()
+}
+
+func longestRun(text string) int {
+	longest, current := 0, 0
+	var prev rune = -1
+
+	for _, r := range text {
+		if r == prev {
+			current++
+		} else {
+			current = 1
+			prev = r
+		}
+		longest = max(longest, current)
+	}
+
+	return longest
+}
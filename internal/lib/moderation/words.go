@@ -0,0 +1,118 @@
+package moderation
+
+import (
+	"context"
+	"simple-chat/internal/config"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// WordFilter matches whole words against per-locale lists. Every list is
+// checked, since the language of a message is not known; the locale decides
+// how words are case-folded.
+type WordFilter struct {
+	action Action
+	lists  []wordList
+}
+
+type wordList struct {
+	tag   language.Tag
+	words map[string]struct{}
+}
+
+func NewWordFilter(cfg config.WordFilter) (*WordFilter, error) {
+	action, err := ParseAction(cfg.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &WordFilter{action: action}
+	for locale, words := range cfg.Words {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return nil, err
+		}
+
+		list := wordList{tag: tag, words: make(map[string]struct{}, len(words))}
+		lower := cases.Lower(tag)
+		for _, word := range words {
+			list.words[lower.String(strings.TrimSpace(word))] = struct{}{}
+		}
+		filter.lists = append(filter.lists, list)
+	}
+
+	return filter, nil
+}
+
+func (f *WordFilter) Moderate(_ context.Context, msg Message) (Decision, error) {
+	if len(f.lists) == 0 {
+		return Decision{Action: Allow, Text: msg.Text}, nil
+	}
+
+	// Casers keep state, so they are created per call rather than shared.
+	lowers := make([]cases.Caser, len(f.lists))
+	for idx, list := range f.lists {
+		lowers[idx] = cases.Lower(list.tag)
+	}
+
+	var masked strings.Builder
+	found := false
+	last := 0
+
+	for _, span := range wordSpans(msg.Text) {
+		word := msg.Text[span[0]:span[1]]
+		if !f.matches(word, lowers) {
+			continue
+		}
+		found = true
+		masked.WriteString(msg.Text[last:span[0]])
+		masked.WriteString(strings.Repeat("*", utf8.RuneCountInString(word)))
+		last = span[1]
+	}
+
+	if !found {
+		return Decision{Action: Allow, Text: msg.Text}, nil
+	}
+	masked.WriteString(msg.Text[last:])
+
+	decision := Decision{Action: f.action, Reason: ReasonProfanity, Text: msg.Text}
+	if f.action == Mask {
+		decision.Text = masked.String()
+	}
+	return decision, nil
+}
+
+func (f *WordFilter) matches(word string, lowers []cases.Caser) bool {
+	for idx, list := range f.lists {
+		if _, ok := list.words[lowers[idx].String(word)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// wordSpans returns the byte offsets of runs of letters, digits and marks.
+func wordSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+
+	for idx, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+		switch {
+		case inWord && start < 0:
+			start = idx
+		case !inWord && start >= 0:
+			spans = append(spans, [2]int{start, idx})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+
+	return spans
+}
@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"context"
+	"simple-chat/internal/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+type lockoutEntry struct {
+	failures    int
+	firstFail   time.Time
+	lastFail    time.Time
+	lockouts    int
+	lockedUntil time.Time
+}
+
+// AuthThrottle protects the login and register endpoints. Failed logins are
+// counted per IP and per email; reaching the limit locks the key out for a
+// period that doubles with every consecutive lockout.
+type AuthThrottle struct {
+	cfg     config.AuthThrottle
+	limiter Limiter
+
+	mu        sync.Mutex
+	entries   map[string]*lockoutEntry
+	lastSweep time.Time
+}
+
+func NewAuthThrottle(limiter Limiter, cfg config.AuthThrottle) *AuthThrottle {
+	return &AuthThrottle{
+		cfg:       cfg,
+		limiter:   limiter,
+		entries:   make(map[string]*lockoutEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// LoginLocked returns how long logins from the IP or for the email are still
+// locked out, or zero.
+func (t *AuthThrottle) LoginLocked(ip string, email string) time.Duration {
+	if !t.cfg.Enabled {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var locked time.Duration
+	for _, key := range []string{ipKey(ip), emailKey(email)} {
+		if e, ok := t.entries[key]; ok && e.lockedUntil.After(now) {
+			locked = max(locked, e.lockedUntil.Sub(now))
+		}
+	}
+	return locked
+}
+
+// LoginFailed records a failed login and returns the lockout it triggered
+// together with the locked key, or zero when no new lockout started.
+func (t *AuthThrottle) LoginFailed(ip string, email string) (time.Duration, string) {
+	if !t.cfg.Enabled {
+		return 0, ""
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.sweep(now)
+
+	var (
+		locked    time.Duration
+		lockedKey string
+	)
+	limits := map[string]int{
+		ipKey(ip):       t.cfg.MaxFailuresPerIP,
+		emailKey(email): t.cfg.MaxFailuresPerEmail,
+	}
+	for key, maxFailures := range limits {
+		if d := t.fail(key, maxFailures, now); d > locked {
+			locked, lockedKey = d, key
+		}
+	}
+	return locked, lockedKey
+}
+
+// LoginSucceeded clears the failures of the email. The IP counter is kept, so
+// that logging into an own account does not reset a guessing attempt.
+func (t *AuthThrottle) LoginSucceeded(email string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.entries, emailKey(email))
+}
+
+func (t *AuthThrottle) AllowRegister(ctx context.Context, ip string) (Result, error) {
+	if !t.cfg.Enabled {
+		return Result{Allowed: true}, nil
+	}
+	return t.limiter.Allow(ctx, "register:"+ipKey(ip), Rate(t.cfg.RegisterPerIP))
+}
+
+func (t *AuthThrottle) fail(key string, maxFailures int, now time.Time) time.Duration {
+	if maxFailures <= 0 {
+		return 0
+	}
+
+	e, ok := t.entries[key]
+	if !ok {
+		e = &lockoutEntry{}
+		t.entries[key] = e
+	}
+
+	if now.Sub(e.firstFail) > t.cfg.FailureWindow {
+		e.failures = 0
+		e.firstFail = now
+	}
+	e.failures++
+	e.lastFail = now
+
+	if e.failures < maxFailures {
+		return 0
+	}
+
+	lockout := t.cfg.BaseLockout << min(e.lockouts, 30)
+	if lockout <= 0 || lockout > t.cfg.MaxLockout {
+		lockout = t.cfg.MaxLockout
+	}
+	e.lockouts++
+	e.failures = 0
+	e.lockedUntil = now.Add(lockout)
+
+	return lockout
+}
+
+// sweep forgets keys without failures for ResetAfter, which also resets their
+// lockout escalation.
+func (t *AuthThrottle) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < sweepInterval {
+		return
+	}
+	t.lastSweep = now
+
+	for key, e := range t.entries {
+		if e.lockedUntil.Before(now) && now.Sub(e.lastFail) > t.cfg.ResetAfter {
+			delete(t.entries, key)
+		}
+	}
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func emailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
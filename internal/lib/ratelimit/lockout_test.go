@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"context"
+	"simple-chat/internal/config"
+	"testing"
+	"time"
+)
+
+func testThrottleConfig() config.AuthThrottle {
+	return config.AuthThrottle{
+		Enabled:             true,
+		MaxFailuresPerIP:    10,
+		MaxFailuresPerEmail: 3,
+		FailureWindow:       time.Minute,
+		BaseLockout:         time.Minute,
+		MaxLockout:          5 * time.Minute,
+		ResetAfter:          time.Hour,
+		RegisterPerIP:       config.RegisterRate{Events: 1, Period: time.Hour, Burst: 2},
+	}
+}
+
+func TestAuthThrottleLoginFailed(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         func(*config.AuthThrottle)
+		failures    int
+		wantLockout time.Duration
+		wantKey     string
+	}{
+		{
+			name:     "below the limit",
+			failures: 2,
+		},
+		{
+			name:        "email limit reached",
+			failures:    3,
+			wantLockout: time.Minute,
+			wantKey:     "email:user@example.com",
+		},
+		{
+			name:        "second lockout doubles",
+			failures:    6,
+			wantLockout: 2 * time.Minute,
+			wantKey:     "email:user@example.com",
+		},
+		{
+			name:        "lockout is capped",
+			failures:    15,
+			wantLockout: 5 * time.Minute,
+			wantKey:     "email:user@example.com",
+		},
+		{
+			name:        "ip limit reached",
+			cfg:         func(c *config.AuthThrottle) { c.MaxFailuresPerEmail = 0; c.MaxFailuresPerIP = 2 },
+			failures:    2,
+			wantLockout: time.Minute,
+			wantKey:     "ip:10.0.0.1",
+		},
+		{
+			name:     "disabled",
+			cfg:      func(c *config.AuthThrottle) { c.Enabled = false },
+			failures: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testThrottleConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			throttle := NewAuthThrottle(NewMemoryLimiter(), cfg)
+
+			var (
+				lockout time.Duration
+				key     string
+			)
+			for range tt.failures {
+				if d, k := throttle.LoginFailed("10.0.0.1", " User@Example.com "); d > 0 {
+					lockout, key = d, k
+				}
+			}
+
+			if lockout != tt.wantLockout || key != tt.wantKey {
+				t.Errorf("got lockout %v for %q, want %v for %q", lockout, key, tt.wantLockout, tt.wantKey)
+			}
+			if locked := throttle.LoginLocked("10.0.0.1", "user@example.com"); (locked > 0) != (tt.wantLockout > 0) {
+				t.Errorf("LoginLocked = %v, want locked %v", locked, tt.wantLockout > 0)
+			}
+		})
+	}
+}
+
+func TestAuthThrottleLoginSucceeded(t *testing.T) {
+	throttle := NewAuthThrottle(NewMemoryLimiter(), testThrottleConfig())
+
+	throttle.LoginFailed("10.0.0.1", "user@example.com")
+	throttle.LoginFailed("10.0.0.1", "user@example.com")
+	throttle.LoginSucceeded("user@example.com")
+
+	if d, _ := throttle.LoginFailed("10.0.0.1", "user@example.com"); d != 0 {
+		t.Errorf("failures survived a successful login, got lockout %v", d)
+	}
+	if e := throttle.entries["ip:10.0.0.1"]; e == nil || e.failures != 3 {
+		t.Error("successful login reset the ip failures")
+	}
+}
+
+func TestAuthThrottleFailureWindow(t *testing.T) {
+	throttle := NewAuthThrottle(NewMemoryLimiter(), testThrottleConfig())
+
+	throttle.LoginFailed("10.0.0.1", "user@example.com")
+	throttle.LoginFailed("10.0.0.1", "user@example.com")
+	throttle.entries["email:user@example.com"].firstFail = time.Now().Add(-2 * time.Minute)
+
+	if d, _ := throttle.LoginFailed("10.0.0.1", "user@example.com"); d != 0 {
+		t.Errorf("failures outside the window counted, got lockout %v", d)
+	}
+}
+
+func TestAuthThrottleAllowRegister(t *testing.T) {
+	throttle := NewAuthThrottle(NewMemoryLimiter(), testThrottleConfig())
+
+	for i, want := range []bool{true, true, false} {
+		res, err := throttle.AllowRegister(context.Background(), "10.0.0.1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Allowed != want {
+			t.Errorf("registration %d allowed = %v, want %v", i+1, res.Allowed, want)
+		}
+	}
+
+	if res, _ := throttle.AllowRegister(context.Background(), "10.0.0.2"); !res.Allowed {
+		t.Error("limit of one ip applied to another")
+	}
+}
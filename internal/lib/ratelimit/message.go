@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"simple-chat/internal/config"
+)
+
+// MessagePolicy applies the per user, per connection and per chat limits to
+// message sends.
+type MessagePolicy struct {
+	limiter Limiter
+	cfg     config.RateLimit
+}
+
+func NewMessagePolicy(limiter Limiter, cfg config.RateLimit) *MessagePolicy {
+	return &MessagePolicy{
+		limiter: limiter,
+		cfg:     cfg,
+	}
+}
+
+// AllowMessage checks every applicable limit and charges the send to all of
+// them only when none is exhausted; connID is empty for sends that do not
+// come from a websocket. Callers must make sure the user may post to the chat
+// first, so outsiders cannot spend the chat's budget.
+func (p *MessagePolicy) AllowMessage(ctx context.Context, userID int64, chatID int64, connID string) (Result, error) {
+	if !p.cfg.Enabled {
+		return Result{Allowed: true}, nil
+	}
+
+	limits := []Limit{
+		{Key: fmt.Sprintf("message:user:%d", userID), Rate: Rate(p.cfg.PerUser)},
+		{Key: fmt.Sprintf("message:chat:%d", chatID), Rate: Rate(p.cfg.PerChat)},
+	}
+	if connID != "" {
+		limits = append(limits, Limit{Key: "message:conn:" + connID, Rate: Rate(p.cfg.PerConnection)})
+	}
+
+	return p.limiter.AllowAll(ctx, limits)
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"simple-chat/internal/config"
+	"testing"
+	"time"
+)
+
+func TestMessagePolicyAllowMessage(t *testing.T) {
+	cfg := config.RateLimit{
+		Enabled:       true,
+		PerUser:       config.UserRate{Events: 1, Period: time.Hour, Burst: 5},
+		PerConnection: config.ConnectionRate{Events: 1, Period: time.Hour, Burst: 2},
+		PerChat:       config.ChatRate{Events: 1, Period: time.Hour, Burst: 3},
+	}
+
+	tests := []struct {
+		name    string
+		cfg     func(*config.RateLimit)
+		connID  string
+		calls   int
+		allowed int
+	}{
+		{
+			name:    "chat limit applies without a connection",
+			calls:   5,
+			allowed: 3,
+		},
+		{
+			name:    "connection limit applies to websocket sends",
+			connID:  "conn",
+			calls:   5,
+			allowed: 2,
+		},
+		{
+			name:    "disabled",
+			cfg:     func(c *config.RateLimit) { c.Enabled = false },
+			calls:   10,
+			allowed: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			policy := NewMessagePolicy(NewMemoryLimiter(), cfg)
+
+			allowed := 0
+			for range tt.calls {
+				res, err := policy.AllowMessage(context.Background(), 1, 1, tt.connID)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if res.Allowed {
+					allowed++
+				}
+			}
+
+			if allowed != tt.allowed {
+				t.Errorf("allowed %d of %d messages, want %d", allowed, tt.calls, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestMessagePolicyRejectionKeepsUserBudget(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	policy := NewMessagePolicy(limiter, config.RateLimit{
+		Enabled: true,
+		PerUser: config.UserRate{Events: 1, Period: time.Hour, Burst: 2},
+		PerChat: config.ChatRate{Events: 1, Period: time.Hour, Burst: 1},
+	})
+
+	policy.AllowMessage(context.Background(), 1, 1, "")
+	for range 5 {
+		policy.AllowMessage(context.Background(), 1, 1, "")
+	}
+
+	// Sends rejected by the exhausted chat must not drain the user.
+	res, _ := policy.AllowMessage(context.Background(), 1, 2, "")
+	if !res.Allowed {
+		t.Error("user budget was spent by rejected sends")
+	}
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rate allows Events per Period on average with bursts of up to Burst events.
+// A rate with no events is unlimited.
+type Rate struct {
+	Events int
+	Period time.Duration
+	Burst  int
+}
+
+func (r Rate) Unlimited() bool {
+	return r.Events <= 0 || r.Period <= 0
+}
+
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Limit is a rate applied to the bucket under Key.
+type Limit struct {
+	Key  string
+	Rate Rate
+}
+
+// Limiter is a token bucket store. The in-memory implementation serves a
+// single instance; multi-instance deployments can plug in a shared backend.
+// AllowAll takes a token from every bucket only when all of them have one,
+// so a rejected event is not charged to any limit.
+type Limiter interface {
+	Allow(ctx context.Context, key string, rate Rate) (Result, error)
+	AllowAll(ctx context.Context, limits []Limit) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// idle is how long the bucket takes to refill completely.
+	idle time.Duration
+}
+
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+const sweepInterval = time.Minute
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, rate Rate) (Result, error) {
+	return l.AllowAll(ctx, []Limit{{Key: key, Rate: rate}})
+}
+
+func (l *MemoryLimiter) AllowAll(ctx context.Context, limits []Limit) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	buckets := make([]*bucket, 0, len(limits))
+	var retryAfter time.Duration
+	for _, limit := range limits {
+		if limit.Rate.Unlimited() {
+			continue
+		}
+
+		perSecond := float64(limit.Rate.Events) / limit.Rate.Period.Seconds()
+		burst := float64(max(limit.Rate.Burst, 1))
+
+		b, ok := l.buckets[limit.Key]
+		if !ok {
+			b = &bucket{tokens: burst, updated: now}
+			l.buckets[limit.Key] = b
+		}
+
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+		b.updated = now
+		b.idle = time.Duration(burst / perSecond * float64(time.Second))
+		buckets = append(buckets, b)
+
+		if b.tokens < 1 {
+			wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+			retryAfter = max(retryAfter, wait)
+		}
+	}
+
+	if retryAfter > 0 {
+		return Result{Allowed: false, RetryAfter: retryAfter}, nil
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return Result{Allowed: true}, nil
+}
+
+// sweep drops buckets that have been idle long enough to refill completely,
+// as they are indistinguishable from new ones.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) > max(b.idle, sweepInterval) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    Rate
+		calls   int
+		allowed int
+	}{
+		{
+			name:    "burst is allowed",
+			rate:    Rate{Events: 1, Period: time.Hour, Burst: 3},
+			calls:   3,
+			allowed: 3,
+		},
+		{
+			name:    "events beyond the burst are rejected",
+			rate:    Rate{Events: 1, Period: time.Hour, Burst: 3},
+			calls:   5,
+			allowed: 3,
+		},
+		{
+			name:    "zero burst allows a single event",
+			rate:    Rate{Events: 1, Period: time.Hour},
+			calls:   2,
+			allowed: 1,
+		},
+		{
+			name:    "rate without events is unlimited",
+			rate:    Rate{Period: time.Hour, Burst: 1},
+			calls:   10,
+			allowed: 10,
+		},
+		{
+			name:    "rate without period is unlimited",
+			rate:    Rate{Events: 1, Burst: 1},
+			calls:   10,
+			allowed: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewMemoryLimiter()
+
+			allowed := 0
+			for range tt.calls {
+				res, err := l.Allow(context.Background(), "key", tt.rate)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if res.Allowed {
+					allowed++
+				} else if res.RetryAfter <= 0 {
+					t.Errorf("rejected result has no retry after")
+				}
+			}
+
+			if allowed != tt.allowed {
+				t.Errorf("allowed %d of %d events, want %d", allowed, tt.calls, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterRefill(t *testing.T) {
+	l := NewMemoryLimiter()
+	rate := Rate{Events: 1, Period: time.Second, Burst: 1}
+
+	if res, _ := l.Allow(context.Background(), "key", rate); !res.Allowed {
+		t.Fatal("first event was rejected")
+	}
+	if res, _ := l.Allow(context.Background(), "key", rate); res.Allowed {
+		t.Fatal("second event was allowed before the bucket refilled")
+	}
+
+	l.buckets["key"].updated = time.Now().Add(-time.Second)
+
+	if res, _ := l.Allow(context.Background(), "key", rate); !res.Allowed {
+		t.Error("event was rejected after the bucket refilled")
+	}
+}
+
+func TestMemoryLimiterAllowAll(t *testing.T) {
+	loose := Rate{Events: 1, Period: time.Hour, Burst: 5}
+	tight := Rate{Events: 1, Period: time.Hour, Burst: 1}
+
+	tests := []struct {
+		name       string
+		limits     []Limit
+		calls      int
+		wantTokens map[string]float64
+	}{
+		{
+			name:       "every bucket is charged",
+			limits:     []Limit{{Key: "a", Rate: loose}, {Key: "b", Rate: loose}},
+			calls:      2,
+			wantTokens: map[string]float64{"a": 3, "b": 3},
+		},
+		{
+			name:       "rejection charges no bucket",
+			limits:     []Limit{{Key: "a", Rate: loose}, {Key: "b", Rate: tight}},
+			calls:      4,
+			wantTokens: map[string]float64{"a": 4, "b": 0},
+		},
+		{
+			name:       "order does not change the charge",
+			limits:     []Limit{{Key: "b", Rate: tight}, {Key: "a", Rate: loose}},
+			calls:      4,
+			wantTokens: map[string]float64{"a": 4, "b": 0},
+		},
+		{
+			name:       "unlimited rates are skipped",
+			limits:     []Limit{{Key: "a", Rate: loose}, {Key: "b", Rate: Rate{}}},
+			calls:      1,
+			wantTokens: map[string]float64{"a": 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewMemoryLimiter()
+
+			for range tt.calls {
+				if _, err := l.AllowAll(context.Background(), tt.limits); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if len(l.buckets) != len(tt.wantTokens) {
+				t.Errorf("got %d buckets, want %d", len(l.buckets), len(tt.wantTokens))
+			}
+			for key, want := range tt.wantTokens {
+				b, ok := l.buckets[key]
+				if !ok {
+					t.Errorf("bucket %q is missing", key)
+					continue
+				}
+				// Tokens refill while the test runs, so only whole tokens count.
+				if got := float64(int(b.tokens)); got != want {
+					t.Errorf("bucket %q has %v tokens, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterAllowAllRetryAfter(t *testing.T) {
+	l := NewMemoryLimiter()
+	limits := []Limit{
+		{Key: "fast", Rate: Rate{Events: 1, Period: time.Second, Burst: 1}},
+		{Key: "slow", Rate: Rate{Events: 1, Period: time.Minute, Burst: 1}},
+	}
+
+	l.AllowAll(context.Background(), limits)
+	res, _ := l.AllowAll(context.Background(), limits)
+	if res.Allowed {
+		t.Fatal("event was allowed with both buckets empty")
+	}
+	if res.RetryAfter <= 30*time.Second {
+		t.Errorf("retry after %v, want the slowest bucket's wait", res.RetryAfter)
+	}
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	l := NewMemoryLimiter()
+	now := time.Now()
+
+	// The slow bucket takes an hour to refill and the fast one a second.
+	l.Allow(context.Background(), "slow", Rate{Events: 1, Period: time.Hour, Burst: 1})
+	l.Allow(context.Background(), "fast", Rate{Events: 1, Period: time.Second, Burst: 1})
+	l.buckets["slow"].updated = now.Add(-10 * time.Minute)
+	l.buckets["fast"].updated = now.Add(-10 * time.Minute)
+	l.lastSweep = now.Add(-2 * sweepInterval)
+
+	l.Allow(context.Background(), "other", Rate{Events: 1, Period: time.Second, Burst: 1})
+
+	if _, ok := l.buckets["slow"]; !ok {
+		t.Error("slow bucket was swept before it refilled")
+	}
+	if _, ok := l.buckets["fast"]; ok {
+		t.Error("refilled fast bucket was not swept")
+	}
+}
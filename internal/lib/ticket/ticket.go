@@ -0,0 +1,55 @@
+package ticket
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/cache"
+	"time"
+)
+
+var ErrInvalidTicket = errors.New("ticket is invalid or expired")
+
+// Ticket is a short-lived, single-use credential that lets a browser open a
+// websocket without sending an Authorization header.
+type Ticket struct {
+	User  models.User
+	Token string
+}
+
+type Store struct {
+	ttl     time.Duration
+	tickets *cache.Cache[string, Ticket]
+}
+
+func NewStore(ttl time.Duration, maxSize int) *Store {
+	return &Store{
+		ttl:     ttl,
+		tickets: cache.New[string, Ticket](ttl, maxSize),
+	}
+}
+
+func (s *Store) TTL() time.Duration {
+	return s.ttl
+}
+
+func (s *Store) Issue(user models.User, token string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	id := base64.RawURLEncoding.EncodeToString(buf)
+	s.tickets.Set(id, Ticket{User: user, Token: token})
+
+	return id, nil
+}
+
+func (s *Store) Redeem(id string) (Ticket, error) {
+	ticket, ok := s.tickets.Take(id)
+	if !ok {
+		return Ticket{}, ErrInvalidTicket
+	}
+	return ticket, nil
+}
@@ -0,0 +1,162 @@
+package chat
+
+import (
+	"context"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateChannel creates a channel owned by creator. Subscribers join on
+// their own with Subscribe.
+func (s *ChatService) CreateChannel(ctx context.Context, creator int64, req dto.CreateChannelRequest) (chat models.Chat, err error) {
+	const op = "chat.service.CreateChannel"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			chat = models.Chat{}
+			return
+		}
+	}()
+
+	now := time.Now().UTC()
+	chatID, err := s.chatDB.CreateChat(ctx, tx, &dto.Chat{
+		Type:        models.ChatChannel,
+		Title:       req.Title,
+		Description: req.Description,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		s.log.Error("failed to create chat", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+
+	if err = s.chatDB.AddMember(ctx, tx, chatID, creator, models.RoleOwner, now); err != nil {
+		s.log.Error("failed to add chat owner", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+
+	if _, err = s.systemMessage(ctx, tx, chatID, models.SystemEvent{Action: models.EventChatCreated, Actor: creator}, now); err != nil {
+		return models.Chat{}, err
+	}
+
+	chat, err = s.chatDB.GetChatByID(ctx, tx, chatID)
+	if err != nil {
+		s.log.Error("failed to get chat", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+
+	return chat, nil
+}
+
+// Subscribe adds the user to a channel as a read-only member and returns
+// the channel with its new subscriber count. Unlike joining a group, it is
+// not recorded in the timeline.
+func (s *ChatService) Subscribe(ctx context.Context, userID int64, chatID int64) (chat models.Chat, err error) {
+	const op = "chat.service.Subscribe"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			chat = models.Chat{}
+			return
+		}
+	}()
+
+	if err = s.channel(ctx, tx, chatID); err != nil {
+		return models.Chat{}, err
+	}
+
+	if err = s.chatDB.AddMember(ctx, tx, chatID, userID, models.RoleMember, time.Now().UTC()); err != nil {
+		s.log.Error("failed to add chat member", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+
+	chat, err = s.chatDB.GetChatByID(ctx, tx, chatID)
+	if err != nil {
+		s.log.Error("failed to get chat", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+
+	return chat, nil
+}
+
+// Unsubscribe removes the user from a channel. The owner has to transfer
+// ownership first.
+func (s *ChatService) Unsubscribe(ctx context.Context, userID int64, chatID int64) (err error) {
+	const op = "chat.service.Unsubscribe"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	chat, member, err := s.member(ctx, tx, userID, chatID)
+	if err != nil {
+		return err
+	}
+	if chat.Type != models.ChatChannel {
+		err = ErrNotChannel
+		return err
+	}
+	if member.Role == models.RoleOwner {
+		err = ErrOwnerCannotLeave
+		return err
+	}
+
+	if err = s.chatDB.RemoveMember(ctx, tx, chatID, userID); err != nil {
+		s.log.Error("failed to remove chat member", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+// channel returns ErrNotChannel unless the chat is a channel.
+func (s *ChatService) channel(ctx context.Context, tx pgx.Tx, chatID int64) error {
+	const op = "chat.service.channel"
+
+	chat, err := s.chatDB.GetChatByID(ctx, tx, chatID)
+	if err != nil {
+		s.log.Error("failed to get chat", sl.OpErr(op, err))
+		return err
+	}
+	if chat.Type != models.ChatChannel {
+		return ErrNotChannel
+	}
+
+	return nil
+}
@@ -7,34 +7,87 @@ import (
 	"simple-chat/internal/domain/dto"
 	"simple-chat/internal/domain/models"
 	"simple-chat/internal/lib/logger/sl"
+	chatStorage "simple-chat/internal/storage/chat"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrNotChatMember = errors.New("user is not a member of the chat")
+	// ErrBlocked is returned when the other participant blocked the user.
+	ErrBlocked = errors.New("user is blocked")
+	// ErrNotAllowed is returned when the user's role in the chat does not
+	// permit the action.
+	ErrNotAllowed       = errors.New("action is not allowed for the user's role in the chat")
+	ErrNotGroupChat     = errors.New("chat is not a group chat")
+	ErrNotChannel       = errors.New("chat is not a channel")
+	ErrOwnerCannotLeave = errors.New("the owner must transfer ownership before leaving the chat")
+)
+
 type ChatService struct {
-	log    *slog.Logger
-	chatDB ChatDB
-	pool   *pgxpool.Pool
+	log        *slog.Logger
+	chatDB     ChatDB
+	userDB     UserDB
+	messagesDB MessagesDB
+	inviteDB   InviteDB
+	pool       *pgxpool.Pool
 }
 
 type ChatDB interface {
 	CreateChat(ctx context.Context, tx pgx.Tx, chat *dto.Chat) (int64, error)
 	GetChatByID(ctx context.Context, tx pgx.Tx, chatID int64) (models.Chat, error)
-	GetUserChats(ctx context.Context, tx pgx.Tx, userID int64, limit int, offset int) ([]models.Chat, error)
+	GetUserChats(ctx context.Context, tx pgx.Tx, userID int64, filter dto.ChatFilter, limit int, offset int) ([]models.Chat, error)
 	UpdateChatMessage(ctx context.Context, tx pgx.Tx, chatID int64, message string, updatedAt time.Time) error
+	UpdateChatInfo(ctx context.Context, tx pgx.Tx, chatID int64, info dto.ChatInfoRequest) error
+	SetMessageTTL(ctx context.Context, tx pgx.Tx, chatID int64, ttl int) error
+	GetChatSettings(ctx context.Context, tx pgx.Tx, chatID int64, userID int64) (models.ChatSettings, error)
+	SaveChatSettings(ctx context.Context, tx pgx.Tx, chatID int64, userID int64, settings models.ChatSettings, updatedAt time.Time) error
+	AddMember(ctx context.Context, tx pgx.Tx, chatID int64, userID int64, role string, joinedAt time.Time) error
+	RemoveMember(ctx context.Context, tx pgx.Tx, chatID int64, userID int64) error
+	SetMemberRole(ctx context.Context, tx pgx.Tx, chatID int64, userID int64, role string) error
+	GetMember(ctx context.Context, tx pgx.Tx, chatID int64, userID int64) (models.ChatMember, error)
+	GetMembers(ctx context.Context, tx pgx.Tx, chatID int64) ([]models.ChatMember, error)
 }
 
-func NewChatService(log *slog.Logger, chatDB ChatDB, pool *pgxpool.Pool) *ChatService {
+type UserDB interface {
+	IsBlocked(ctx context.Context, tx pgx.Tx, blocker int64, blocked int64) (bool, error)
+	GetUsers(ctx context.Context, tx pgx.Tx, userIDs []int64) ([]models.User, error)
+}
+
+type MessagesDB interface {
+	CreateMessage(ctx context.Context, tx pgx.Tx, message dto.Message) (int64, error)
+}
+
+type InviteDB interface {
+	CreateInvite(ctx context.Context, tx pgx.Tx, invite models.Invite) (int64, error)
+	GetChatInvites(ctx context.Context, tx pgx.Tx, chatID int64) ([]models.Invite, error)
+	RevokeInvite(ctx context.Context, tx pgx.Tx, chatID int64, inviteID int64, revokedAt time.Time) error
+	RedeemInvite(ctx context.Context, tx pgx.Tx, token string, at time.Time) (int64, error)
+}
+
+func NewChatService(
+	log *slog.Logger,
+	chatDB ChatDB,
+	userDB UserDB,
+	messagesDB MessagesDB,
+	inviteDB InviteDB,
+	pool *pgxpool.Pool,
+) *ChatService {
 	return &ChatService{
-		log:    log,
-		chatDB: chatDB,
-		pool:   pool,
+		log:        log,
+		chatDB:     chatDB,
+		userDB:     userDB,
+		messagesDB: messagesDB,
+		inviteDB:   inviteDB,
+		pool:       pool,
 	}
 }
 
-func (s *ChatService) CreateChat(ctx context.Context, chat *dto.Chat) (chatID int64, err error) {
+// CreateChat creates a chat on behalf of creator, who must be one of its
+// participants and must not be blocked by the other one.
+func (s *ChatService) CreateChat(ctx context.Context, creator int64, chat *dto.Chat) (chatID int64, err error) {
 	const op = "chat.service.CreateChat"
 
 	tx, err := s.pool.Begin(ctx)
@@ -47,12 +100,32 @@ func (s *ChatService) CreateChat(ctx context.Context, chat *dto.Chat) (chatID in
 			tx.Rollback(ctx)
 			return
 		}
-		if err := tx.Commit(ctx); err != nil {
+		if err = tx.Commit(ctx); err != nil {
 			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
-			return
+			chatID = 0
 		}
 	}()
 
+	if chat.FirstUserID != creator && chat.SecondUserID != creator {
+		err = ErrNotChatMember
+		return 0, err
+	}
+
+	other := chat.FirstUserID
+	if other == creator {
+		other = chat.SecondUserID
+	}
+	blocked, err := s.userDB.IsBlocked(ctx, tx, other, creator)
+	if err != nil {
+		s.log.Error("failed to check block", sl.OpErr(op, err))
+		return 0, err
+	}
+	if blocked {
+		err = ErrBlocked
+		return 0, err
+	}
+
+	chat.Type = models.ChatDirect
 	chatID, err = s.chatDB.CreateChat(ctx, tx, chat)
 	if err != nil {
 		s.log.Error("failed to create chat", sl.OpErr(op, err))
@@ -65,6 +138,19 @@ func (s *ChatService) CreateChat(ctx context.Context, chat *dto.Chat) (chatID in
 		return 0, err
 	}
 
+	for _, userID := range []int64{chat.FirstUserID, chat.SecondUserID} {
+		err = s.chatDB.AddMember(ctx, tx, chatID, userID, models.RoleMember, chat.UpdatedAt)
+		if errors.Is(err, chatStorage.ErrAlreadyMember) {
+			// A chat with oneself has a single member.
+			err = nil
+			continue
+		}
+		if err != nil {
+			s.log.Error("failed to add chat member", sl.OpErr(op, err))
+			return 0, err
+		}
+	}
+
 	return chatID, nil
 }
 
@@ -83,16 +169,40 @@ func (s *ChatService) GetChatByID(ctx context.Context, chatID int64) (models.Cha
 		s.log.Error("failed to get chat", sl.OpErr(op, err))
 		return models.Chat{}, err
 	}
-	if chat == (models.Chat{}) {
+	if chat.ID == 0 {
 		s.log.Error("failed to get chat", sl.OpErr(op, errors.New("chat model is empty")))
 		err = errors.New("chat model is empty")
 		return models.Chat{}, err
 	}
 
+	if chat.Type == models.ChatGroup {
+		chat.Members, err = s.chatDB.GetMembers(ctx, tx, chatID)
+		if err != nil {
+			s.log.Error("failed to get chat members", sl.OpErr(op, err))
+			return models.Chat{}, err
+		}
+	}
+
 	return chat, nil
 }
 
-func (s *ChatService) GetUserChats(ctx context.Context, userID int64, limit int, offset int) ([]models.Chat, error) {
+// CheckMember returns ErrNotChatMember unless the user is a member of the
+// chat.
+func (s *ChatService) CheckMember(ctx context.Context, userID int64, chatID int64) error {
+	const op = "chat.service.CheckMember"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, _, err = s.member(ctx, tx, userID, chatID)
+	return err
+}
+
+func (s *ChatService) GetUserChats(ctx context.Context, userID int64, filter dto.ChatFilter, limit int, offset int) ([]models.Chat, error) {
 	const op = "chat.service.GetUserChats"
 
 	tx, err := s.pool.Begin(ctx)
@@ -102,7 +212,7 @@ func (s *ChatService) GetUserChats(ctx context.Context, userID int64, limit int,
 	}
 	defer tx.Rollback(ctx)
 
-	chats, err := s.chatDB.GetUserChats(ctx, tx, userID, limit, offset)
+	chats, err := s.chatDB.GetUserChats(ctx, tx, userID, filter, limit, offset)
 	if err != nil {
 		s.log.Error("failed to get user chats", sl.OpErr(op, err))
 		return nil, err
@@ -128,7 +238,7 @@ func (s *ChatService) UpdateChatMessage(ctx context.Context, chatID int64, messa
 			tx.Rollback(ctx)
 			return
 		}
-		if err := tx.Commit(ctx); err != nil {
+		if err = tx.Commit(ctx); err != nil {
 			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
 			return
 		}
@@ -142,3 +252,175 @@ func (s *ChatService) UpdateChatMessage(ctx context.Context, chatID int64, messa
 
 	return nil
 }
+
+// UpdateChatInfo changes the chat's title, description and avatar on behalf
+// of a member, or of an owner or admin in group chats, and returns the
+// updated chat.
+func (s *ChatService) UpdateChatInfo(ctx context.Context, userID int64, chatID int64, info dto.ChatInfoRequest) (chat models.Chat, err error) {
+	const op = "chat.service.UpdateChatInfo"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	chat, member, err := s.member(ctx, tx, userID, chatID)
+	if err != nil {
+		return models.Chat{}, err
+	}
+	if chat.Type != models.ChatDirect && !member.CanManage() {
+		err = ErrNotAllowed
+		return models.Chat{}, err
+	}
+
+	if err = s.chatDB.UpdateChatInfo(ctx, tx, chatID, info); err != nil {
+		s.log.Error("failed to update chat info", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+
+	chat, err = s.chatDB.GetChatByID(ctx, tx, chatID)
+	if err != nil {
+		s.log.Error("failed to get chat", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+
+	return chat, nil
+}
+
+// SetMessageTTL changes how long new messages stay in the chat on behalf of
+// a member, or of an owner or admin outside direct chats, and records the
+// change in the timeline. It returns a zero message when the TTL is
+// unchanged.
+func (s *ChatService) SetMessageTTL(ctx context.Context, userID int64, chatID int64, ttl int) (message models.Message, err error) {
+	const op = "chat.service.SetMessageTTL"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			message = models.Message{}
+			return
+		}
+	}()
+
+	chat, member, err := s.member(ctx, tx, userID, chatID)
+	if err != nil {
+		return models.Message{}, err
+	}
+	if chat.Type != models.ChatDirect && !member.CanManage() {
+		err = ErrNotAllowed
+		return models.Message{}, err
+	}
+	if chat.MessageTTL == ttl {
+		return models.Message{}, nil
+	}
+
+	if err = s.chatDB.SetMessageTTL(ctx, tx, chatID, ttl); err != nil {
+		s.log.Error("failed to set message ttl", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+
+	return s.systemMessage(ctx, tx, chatID, models.SystemEvent{Action: models.EventTTLChanged, Actor: userID, TTL: ttl}, time.Now().UTC())
+}
+
+func (s *ChatService) GetChatSettings(ctx context.Context, userID int64, chatID int64) (models.ChatSettings, error) {
+	const op = "chat.service.GetChatSettings"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.ChatSettings{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, _, err := s.member(ctx, tx, userID, chatID); err != nil {
+		return models.ChatSettings{}, err
+	}
+
+	settings, err := s.chatDB.GetChatSettings(ctx, tx, chatID, userID)
+	if err != nil {
+		s.log.Error("failed to get chat settings", sl.OpErr(op, err))
+		return models.ChatSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// UpdateChatSettings replaces the user's settings for the chat.
+func (s *ChatService) UpdateChatSettings(ctx context.Context, userID int64, chatID int64, req dto.ChatSettingsRequest) (settings models.ChatSettings, err error) {
+	const op = "chat.service.UpdateChatSettings"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.ChatSettings{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	if _, _, err = s.member(ctx, tx, userID, chatID); err != nil {
+		return models.ChatSettings{}, err
+	}
+
+	settings = models.ChatSettings{
+		MutedUntil:  req.MutedUntil,
+		Archived:    req.Archived,
+		PinnedOrder: req.PinnedOrder,
+	}
+	if err = s.chatDB.SaveChatSettings(ctx, tx, chatID, userID, settings, time.Now().UTC()); err != nil {
+		s.log.Error("failed to save chat settings", sl.OpErr(op, err))
+		return models.ChatSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// member returns the chat and the user's membership in it, or
+// ErrNotChatMember when the user is not a member.
+func (s *ChatService) member(ctx context.Context, tx pgx.Tx, userID int64, chatID int64) (models.Chat, models.ChatMember, error) {
+	const op = "chat.service.member"
+
+	chat, err := s.chatDB.GetChatByID(ctx, tx, chatID)
+	if err != nil {
+		s.log.Error("failed to get chat", sl.OpErr(op, err))
+		return models.Chat{}, models.ChatMember{}, err
+	}
+
+	member, err := s.chatDB.GetMember(ctx, tx, chatID, userID)
+	if err != nil {
+		if errors.Is(err, chatStorage.ErrMemberNotFound) {
+			return models.Chat{}, models.ChatMember{}, ErrNotChatMember
+		}
+		s.log.Error("failed to get chat member", sl.OpErr(op, err))
+		return models.Chat{}, models.ChatMember{}, err
+	}
+
+	return chat, member, nil
+}
@@ -0,0 +1,174 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"time"
+)
+
+// CreateInvite creates an invite link to a group chat on behalf of an owner
+// or admin.
+func (s *ChatService) CreateInvite(ctx context.Context, actor int64, chatID int64, req dto.CreateInviteRequest) (invite models.Invite, err error) {
+	const op = "chat.service.CreateInvite"
+
+	token, err := inviteToken()
+	if err != nil {
+		s.log.Error("failed to generate invite token", sl.OpErr(op, err))
+		return models.Invite{}, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Invite{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			invite = models.Invite{}
+			return
+		}
+	}()
+
+	if _, err = s.manager(ctx, tx, actor, chatID); err != nil {
+		return models.Invite{}, err
+	}
+
+	invite = models.Invite{
+		ChatID:    chatID,
+		Token:     token,
+		CreatedBy: actor,
+		ExpiresAt: req.ExpiresAt,
+		MaxUses:   req.MaxUses,
+		CreatedAt: time.Now().UTC(),
+	}
+	invite.ID, err = s.inviteDB.CreateInvite(ctx, tx, invite)
+	if err != nil {
+		s.log.Error("failed to create invite", sl.OpErr(op, err))
+		return models.Invite{}, err
+	}
+
+	return invite, nil
+}
+
+// GetInvites lists the chat's active invites to an owner or admin. Expired
+// and used up invites are included so their stats can be seen.
+func (s *ChatService) GetInvites(ctx context.Context, actor int64, chatID int64) ([]models.Invite, error) {
+	const op = "chat.service.GetInvites"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := s.manager(ctx, tx, actor, chatID); err != nil {
+		return nil, err
+	}
+
+	invites, err := s.inviteDB.GetChatInvites(ctx, tx, chatID)
+	if err != nil {
+		s.log.Error("failed to get invites", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+func (s *ChatService) RevokeInvite(ctx context.Context, actor int64, chatID int64, inviteID int64) (err error) {
+	const op = "chat.service.RevokeInvite"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	if _, err = s.manager(ctx, tx, actor, chatID); err != nil {
+		return err
+	}
+
+	if err = s.inviteDB.RevokeInvite(ctx, tx, chatID, inviteID, time.Now().UTC()); err != nil {
+		s.log.Error("failed to revoke invite", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+// JoinByInvite redeems an invite token and adds the user to its chat. It
+// returns the system message recording the join, which has no ID for
+// channels.
+func (s *ChatService) JoinByInvite(ctx context.Context, userID int64, token string) (message models.Message, err error) {
+	const op = "chat.service.JoinByInvite"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			message = models.Message{}
+			return
+		}
+	}()
+
+	now := time.Now().UTC()
+	chatID, err := s.inviteDB.RedeemInvite(ctx, tx, token, now)
+	if err != nil {
+		s.log.Error("failed to redeem invite", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+
+	// Members that are already in the chat fail here, which also rolls back
+	// the use counted above.
+	if err = s.chatDB.AddMember(ctx, tx, chatID, userID, models.RoleMember, now); err != nil {
+		s.log.Error("failed to add chat member", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+
+	// Channel subscribers join silently, as with Subscribe.
+	chat, err := s.chatDB.GetChatByID(ctx, tx, chatID)
+	if err != nil {
+		s.log.Error("failed to get chat", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	if chat.Type == models.ChatChannel {
+		return models.Message{ChatID: chatID}, nil
+	}
+
+	return s.systemMessage(ctx, tx, chatID, models.SystemEvent{Action: models.EventMemberJoined, Actor: userID}, now)
+}
+
+func inviteToken() (string, error) {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
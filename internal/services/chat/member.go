@@ -0,0 +1,504 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateGroup creates a group chat owned by creator with the given members
+// and records its creation in the timeline.
+func (s *ChatService) CreateGroup(ctx context.Context, creator int64, req dto.CreateGroupRequest) (chat models.Chat, err error) {
+	const op = "chat.service.CreateGroup"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			chat = models.Chat{}
+		}
+	}()
+
+	now := time.Now().UTC()
+	chatID, err := s.chatDB.CreateChat(ctx, tx, &dto.Chat{
+		Type:      models.ChatGroup,
+		Title:     req.Title,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		s.log.Error("failed to create chat", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+
+	if err = s.chatDB.AddMember(ctx, tx, chatID, creator, models.RoleOwner, now); err != nil {
+		s.log.Error("failed to add chat owner", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+	for _, userID := range distinct(req.Members) {
+		if userID == creator {
+			continue
+		}
+		if err = s.checkNotBlocked(ctx, tx, userID, creator); err != nil {
+			return models.Chat{}, err
+		}
+		if err = s.chatDB.AddMember(ctx, tx, chatID, userID, models.RoleMember, now); err != nil {
+			s.log.Error("failed to add chat member", sl.OpErr(op, err))
+			return models.Chat{}, err
+		}
+	}
+
+	if _, err = s.systemMessage(ctx, tx, chatID, models.SystemEvent{Action: models.EventChatCreated, Actor: creator}, now); err != nil {
+		return models.Chat{}, err
+	}
+
+	chat, err = s.chatDB.GetChatByID(ctx, tx, chatID)
+	if err != nil {
+		s.log.Error("failed to get chat", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+	chat.Members, err = s.chatDB.GetMembers(ctx, tx, chatID)
+	if err != nil {
+		s.log.Error("failed to get chat members", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+
+	return chat, nil
+}
+
+// AddMembers adds users to a group chat on behalf of an owner or admin and
+// returns the system messages recording it.
+func (s *ChatService) AddMembers(ctx context.Context, actor int64, chatID int64, userIDs []int64) (messages []models.Message, err error) {
+	const op = "chat.service.AddMembers"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			messages = nil
+			return
+		}
+	}()
+
+	if _, err = s.manager(ctx, tx, actor, chatID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	for _, userID := range distinct(userIDs) {
+		if err = s.checkNotBlocked(ctx, tx, userID, actor); err != nil {
+			return nil, err
+		}
+		if err = s.chatDB.AddMember(ctx, tx, chatID, userID, models.RoleMember, now); err != nil {
+			s.log.Error("failed to add chat member", sl.OpErr(op, err))
+			return nil, err
+		}
+
+		message, err := s.systemMessage(ctx, tx, chatID, models.SystemEvent{Action: models.EventMemberAdded, Actor: actor, Target: userID}, now)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// RemoveMember removes a user from a group chat. Admins may remove members;
+// only the owner may remove admins, and the owner cannot be removed.
+func (s *ChatService) RemoveMember(ctx context.Context, actor int64, chatID int64, userID int64) (message models.Message, err error) {
+	const op = "chat.service.RemoveMember"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			message = models.Message{}
+			return
+		}
+	}()
+
+	manager, err := s.manager(ctx, tx, actor, chatID)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	target, err := s.chatDB.GetMember(ctx, tx, chatID, userID)
+	if err != nil {
+		s.log.Error("failed to get chat member", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	if target.Role == models.RoleOwner || (target.Role == models.RoleAdmin && manager.Role != models.RoleOwner) {
+		err = ErrNotAllowed
+		return models.Message{}, err
+	}
+
+	if err = s.chatDB.RemoveMember(ctx, tx, chatID, userID); err != nil {
+		s.log.Error("failed to remove chat member", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+
+	return s.systemMessage(ctx, tx, chatID, models.SystemEvent{Action: models.EventMemberRemoved, Actor: actor, Target: userID}, time.Now().UTC())
+}
+
+// LeaveChat removes the user from a group chat. The owner has to transfer
+// ownership first.
+func (s *ChatService) LeaveChat(ctx context.Context, userID int64, chatID int64) (message models.Message, err error) {
+	const op = "chat.service.LeaveChat"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			message = models.Message{}
+			return
+		}
+	}()
+
+	chat, member, err := s.member(ctx, tx, userID, chatID)
+	if err != nil {
+		return models.Message{}, err
+	}
+	if chat.Type != models.ChatGroup {
+		err = ErrNotGroupChat
+		return models.Message{}, err
+	}
+	if member.Role == models.RoleOwner {
+		err = ErrOwnerCannotLeave
+		return models.Message{}, err
+	}
+
+	if err = s.chatDB.RemoveMember(ctx, tx, chatID, userID); err != nil {
+		s.log.Error("failed to remove chat member", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+
+	return s.systemMessage(ctx, tx, chatID, models.SystemEvent{Action: models.EventMemberLeft, Actor: userID}, time.Now().UTC())
+}
+
+// SetMemberRole promotes a member to admin or demotes an admin to member.
+// Only the owner may change roles.
+func (s *ChatService) SetMemberRole(ctx context.Context, actor int64, chatID int64, userID int64, role string) (message models.Message, err error) {
+	const op = "chat.service.SetMemberRole"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			message = models.Message{}
+			return
+		}
+	}()
+
+	if _, err = s.owner(ctx, tx, actor, chatID); err != nil {
+		return models.Message{}, err
+	}
+
+	target, err := s.chatDB.GetMember(ctx, tx, chatID, userID)
+	if err != nil {
+		s.log.Error("failed to get chat member", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	if target.Role == models.RoleOwner {
+		err = ErrNotAllowed
+		return models.Message{}, err
+	}
+	if target.Role == role {
+		return models.Message{}, nil
+	}
+
+	if err = s.chatDB.SetMemberRole(ctx, tx, chatID, userID, role); err != nil {
+		s.log.Error("failed to set member role", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+
+	action := models.EventMemberPromoted
+	if role == models.RoleMember {
+		action = models.EventMemberDemoted
+	}
+	return s.systemMessage(ctx, tx, chatID, models.SystemEvent{Action: action, Actor: actor, Target: userID}, time.Now().UTC())
+}
+
+// TransferOwnership makes another member the owner; the previous owner stays
+// on as an admin.
+func (s *ChatService) TransferOwnership(ctx context.Context, actor int64, chatID int64, userID int64) (message models.Message, err error) {
+	const op = "chat.service.TransferOwnership"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			message = models.Message{}
+			return
+		}
+	}()
+
+	if _, err = s.owner(ctx, tx, actor, chatID); err != nil {
+		return models.Message{}, err
+	}
+	if _, err = s.chatDB.GetMember(ctx, tx, chatID, userID); err != nil {
+		s.log.Error("failed to get chat member", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	if userID == actor {
+		return models.Message{}, nil
+	}
+
+	// Demote first: there can only be one owner at a time.
+	if err = s.chatDB.SetMemberRole(ctx, tx, chatID, actor, models.RoleAdmin); err != nil {
+		s.log.Error("failed to demote owner", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	if err = s.chatDB.SetMemberRole(ctx, tx, chatID, userID, models.RoleOwner); err != nil {
+		s.log.Error("failed to promote owner", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+
+	return s.systemMessage(ctx, tx, chatID, models.SystemEvent{Action: models.EventOwnershipTransferred, Actor: actor, Target: userID}, time.Now().UTC())
+}
+
+// manager returns the actor's membership in a group chat or channel,
+// requiring the owner or an admin.
+func (s *ChatService) manager(ctx context.Context, tx pgx.Tx, actor int64, chatID int64) (models.ChatMember, error) {
+	chat, member, err := s.member(ctx, tx, actor, chatID)
+	if err != nil {
+		return models.ChatMember{}, err
+	}
+	if chat.Type == models.ChatDirect {
+		return models.ChatMember{}, ErrNotGroupChat
+	}
+	if !member.CanManage() {
+		return models.ChatMember{}, ErrNotAllowed
+	}
+
+	return member, nil
+}
+
+// owner returns the actor's membership in a group chat or channel, requiring
+// the owner.
+func (s *ChatService) owner(ctx context.Context, tx pgx.Tx, actor int64, chatID int64) (models.ChatMember, error) {
+	member, err := s.manager(ctx, tx, actor, chatID)
+	if err != nil {
+		return models.ChatMember{}, err
+	}
+	if member.Role != models.RoleOwner {
+		return models.ChatMember{}, ErrNotAllowed
+	}
+
+	return member, nil
+}
+
+// checkNotBlocked returns ErrBlocked when userID blocked actor, who then
+// cannot put them into a chat.
+func (s *ChatService) checkNotBlocked(ctx context.Context, tx pgx.Tx, userID int64, actor int64) error {
+	const op = "chat.service.checkNotBlocked"
+
+	blocked, err := s.userDB.IsBlocked(ctx, tx, userID, actor)
+	if err != nil {
+		s.log.Error("failed to check block", sl.OpErr(op, err))
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+
+	return nil
+}
+
+// systemMessage records event in the chat's timeline and makes it the chat's
+// last message.
+func (s *ChatService) systemMessage(ctx context.Context, tx pgx.Tx, chatID int64, event models.SystemEvent, at time.Time) (models.Message, error) {
+	const op = "chat.service.systemMessage"
+
+	text, err := s.describe(ctx, tx, event)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	// System messages expire with the rest of the timeline in chats with a
+	// TTL.
+	chat, err := s.chatDB.GetChatByID(ctx, tx, chatID)
+	if err != nil {
+		s.log.Error("failed to get chat", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+	var expiresAt *time.Time
+	if chat.MessageTTL > 0 {
+		expires := at.Add(time.Duration(chat.MessageTTL) * time.Second)
+		expiresAt = &expires
+	}
+
+	messageID, err := s.messagesDB.CreateMessage(ctx, tx, dto.Message{
+		ChatID:    chatID,
+		Sender:    event.Actor,
+		Text:      text,
+		CreatedAt: at,
+		ExpiresAt: expiresAt,
+		Type:      models.MessageSystem,
+		Event:     &event,
+	})
+	if err != nil {
+		s.log.Error("failed to create system message", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+
+	if err := s.chatDB.UpdateChatMessage(ctx, tx, chatID, text, at); err != nil {
+		s.log.Error("failed to update chat message", sl.OpErr(op, err))
+		return models.Message{}, err
+	}
+
+	return models.Message{
+		ID:        messageID,
+		ChatID:    chatID,
+		Type:      models.MessageSystem,
+		Sender:    event.Actor,
+		Text:      text,
+		CreatedAt: at,
+		ExpiresAt: expiresAt,
+		Event:     &event,
+	}, nil
+}
+
+// describe renders a system event as English text, such as "Alice added
+// Bob", using the names in the user directory.
+func (s *ChatService) describe(ctx context.Context, tx pgx.Tx, event models.SystemEvent) (string, error) {
+	const op = "chat.service.describe"
+
+	users, err := s.userDB.GetUsers(ctx, tx, []int64{event.Actor, event.Target})
+	if err != nil {
+		s.log.Error("failed to get users", sl.OpErr(op, err))
+		return "", err
+	}
+	names := make(map[int64]string, len(users))
+	for _, user := range users {
+		if user.Name != "" {
+			names[user.UserID] = user.Name
+		}
+	}
+	name := func(userID int64) string {
+		if name, ok := names[userID]; ok {
+			return name
+		}
+		return fmt.Sprintf("user %d", userID)
+	}
+
+	actor, target := name(event.Actor), name(event.Target)
+	switch event.Action {
+	case models.EventChatCreated:
+		return fmt.Sprintf("%s created the chat", actor), nil
+	case models.EventMemberAdded:
+		return fmt.Sprintf("%s added %s", actor, target), nil
+	case models.EventMemberJoined:
+		return fmt.Sprintf("%s joined via an invite link", actor), nil
+	case models.EventMemberRemoved:
+		return fmt.Sprintf("%s removed %s", actor, target), nil
+	case models.EventMemberLeft:
+		return fmt.Sprintf("%s left", actor), nil
+	case models.EventMemberPromoted:
+		return fmt.Sprintf("%s made %s an admin", actor, target), nil
+	case models.EventMemberDemoted:
+		return fmt.Sprintf("%s removed %s as an admin", actor, target), nil
+	case models.EventOwnershipTransferred:
+		return fmt.Sprintf("%s transferred ownership to %s", actor, target), nil
+	case models.EventTTLChanged:
+		if event.TTL == 0 {
+			return fmt.Sprintf("%s turned off disappearing messages", actor), nil
+		}
+		return fmt.Sprintf("%s set messages to disappear after %s", actor, describeTTL(event.TTL)), nil
+	default:
+		return "", errors.New("unknown system event " + event.Action)
+	}
+}
+
+func distinct(ids []int64) []int64 {
+	seen := make(map[int64]struct{}, len(ids))
+	result := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
+}
+
+// describeTTL renders a TTL in seconds in the largest unit that divides it,
+// such as "1 day" or "90 minutes".
+func describeTTL(seconds int) string {
+	units := []struct {
+		name    string
+		seconds int
+	}{
+		{"week", 7 * 24 * 60 * 60},
+		{"day", 24 * 60 * 60},
+		{"hour", 60 * 60},
+		{"minute", 60},
+		{"second", 1},
+	}
+
+	for _, unit := range units {
+		if seconds%unit.seconds != 0 {
+			continue
+		}
+		count := seconds / unit.seconds
+		if count == 1 {
+			return "1 " + unit.name
+		}
+		return fmt.Sprintf("%d %ss", count, unit.name)
+	}
+	return fmt.Sprintf("%d seconds", seconds)
+}
@@ -0,0 +1,57 @@
+package message
+
+import (
+	"context"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"time"
+)
+
+// PurgeExpired deletes up to limit messages that expired at at and returns
+// them with only their ID and chat set. Chats that lost messages get their
+// newest remaining message as the last message.
+func (s *MessageService) PurgeExpired(ctx context.Context, at time.Time, limit int) (purged []models.Message, err error) {
+	const op = "message.service.PurgeExpired"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			purged = nil
+		}
+	}()
+
+	purged, err = s.messagesDB.PurgeExpired(ctx, tx, at, limit)
+	if err != nil {
+		s.log.Error("failed to purge expired messages", sl.OpErr(op, err))
+		return nil, err
+	}
+	if len(purged) == 0 {
+		return nil, nil
+	}
+
+	chatIDs := make([]int64, 0, len(purged))
+	seen := make(map[int64]struct{}, len(purged))
+	for _, message := range purged {
+		if _, ok := seen[message.ChatID]; ok {
+			continue
+		}
+		seen[message.ChatID] = struct{}{}
+		chatIDs = append(chatIDs, message.ChatID)
+	}
+
+	if err = s.chatDB.RefreshChatMessage(ctx, tx, chatIDs); err != nil {
+		s.log.Error("failed to refresh chat message", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return purged, nil
+}
@@ -7,30 +7,238 @@ import (
 	"simple-chat/internal/domain/dto"
 	"simple-chat/internal/domain/models"
 	"simple-chat/internal/lib/logger/sl"
+	"simple-chat/internal/lib/metrics"
+	"simple-chat/internal/lib/moderation"
+	chatService "simple-chat/internal/services/chat"
+	chatStorage "simple-chat/internal/storage/chat"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrSenderSuspended = errors.New("sender is suspended")
+)
+
 type MessageService struct {
-	log        *slog.Logger
-	messagesDB MessagesDB
-	pool       *pgxpool.Pool
+	log         *slog.Logger
+	messagesDB  MessagesDB
+	chatDB      ChatDB
+	reportDB    ReportDB
+	userDB      UserDB
+	scheduledDB ScheduledDB
+	processor   Processor
+	moderator   Moderator
+	pool        *pgxpool.Pool
 }
 
 type MessagesDB interface {
 	CreateMessage(ctx context.Context, tx pgx.Tx, message dto.Message) (int64, error)
-	GetMessageByID(ctx context.Context, tx pgx.Tx, messageID int64) (models.Message, error)
-	GetMessagesByChatID(ctx context.Context, tx pgx.Tx, chatID int64, limit int, offset int) ([]models.Message, error)
+	GetMessageByID(ctx context.Context, tx pgx.Tx, messageID int64, at time.Time) (models.Message, error)
+	GetMessagesByChatID(ctx context.Context, tx pgx.Tx, chatID int64, at time.Time, limit int, offset int) ([]models.Message, error)
 	GetListMessagesByID(ctx context.Context, tx pgx.Tx, messagesID []int64) ([]models.Message, error)
+	PurgeExpired(ctx context.Context, tx pgx.Tx, at time.Time, limit int) ([]models.Message, error)
+}
+
+type ChatDB interface {
+	GetChatByID(ctx context.Context, tx pgx.Tx, chatID int64) (models.Chat, error)
+	GetMember(ctx context.Context, tx pgx.Tx, chatID int64, userID int64) (models.ChatMember, error)
+	UpdateChatMessage(ctx context.Context, tx pgx.Tx, chatID int64, message string, updatedAt time.Time) error
+	UnarchiveChat(ctx context.Context, tx pgx.Tx, chatID int64, at time.Time) error
+	RefreshChatMessage(ctx context.Context, tx pgx.Tx, chatIDs []int64) error
+}
+
+type ReportDB interface {
+	CreateReport(ctx context.Context, tx pgx.Tx, report dto.Report) (int64, error)
+}
+
+type UserDB interface {
+	IsSuspended(ctx context.Context, tx pgx.Tx, userID int64, at time.Time) (bool, error)
+	IsBlocked(ctx context.Context, tx pgx.Tx, blocker int64, blocked int64) (bool, error)
+}
+
+type ScheduledDB interface {
+	CreateScheduled(ctx context.Context, tx pgx.Tx, message models.ScheduledMessage) (int64, error)
+	GetUserScheduled(ctx context.Context, tx pgx.Tx, sender int64) ([]models.ScheduledMessage, error)
+	UpdateScheduled(ctx context.Context, tx pgx.Tx, messageID int64, sender int64, text *string, sendAt *time.Time) (models.ScheduledMessage, error)
+	DeleteScheduled(ctx context.Context, tx pgx.Tx, messageID int64, sender int64) error
+	GetDueScheduled(ctx context.Context, tx pgx.Tx, at time.Time) (models.ScheduledMessage, error)
+	FailScheduled(ctx context.Context, tx pgx.Tx, messageID int64, reason string) error
+}
+
+// Processor normalizes and checks message text before it is stored.
+type Processor interface {
+	Process(text string) (string, error)
+}
+
+// Moderator screens message text after processing and before storage.
+type Moderator interface {
+	Moderate(ctx context.Context, msg moderation.Message) (moderation.Decision, error)
 }
 
-func NewMessageServices(log *slog.Logger, messagesDB MessagesDB, pool *pgxpool.Pool) *MessageService {
+func NewMessageServices(
+	log *slog.Logger,
+	messagesDB MessagesDB,
+	chatDB ChatDB,
+	reportDB ReportDB,
+	userDB UserDB,
+	scheduledDB ScheduledDB,
+	processor Processor,
+	moderator Moderator,
+	pool *pgxpool.Pool,
+) *MessageService {
 	return &MessageService{
-		log:        log,
-		messagesDB: messagesDB,
-		pool:       pool,
+		log:         log,
+		messagesDB:  messagesDB,
+		chatDB:      chatDB,
+		reportDB:    reportDB,
+		userDB:      userDB,
+		scheduledDB: scheduledDB,
+		processor:   processor,
+		moderator:   moderator,
+		pool:        pool,
+	}
+}
+
+// SendMessage runs the text through the processing pipeline and moderation,
+// stores the message and makes it the chat's last message in a single
+// transaction. It is the send path for both REST and websocket clients.
+// Senders must be chat members, not suspended and not blocked by the other
+// participant; rejected messages return a
+// *moderation.RejectedError along with the decision, and flagged ones are
+// queued for review as a report.
+func (s *MessageService) SendMessage(ctx context.Context, message dto.Message) (sent models.Message, decision moderation.Decision, err error) {
+	const op = "message.service.SendMessage"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Message{}, moderation.Decision{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			sent, decision = models.Message{}, moderation.Decision{}
+		}
+	}()
+
+	sent, decision, err = s.send(ctx, tx, message)
+	if err != nil {
+		return models.Message{}, decision, err
+	}
+
+	return sent, decision, nil
+}
+
+// screen runs the text through the processing pipeline and moderation.
+// Rejected messages return a *moderation.RejectedError along with the
+// decision.
+func (s *MessageService) screen(ctx context.Context, message dto.Message) (dto.Message, moderation.Decision, error) {
+	const op = "message.service.screen"
+
+	text, err := s.processor.Process(message.Text)
+	if err != nil {
+		s.log.Debug("message rejected by processing pipeline", sl.OpErr(op, err))
+		return dto.Message{}, moderation.Decision{}, err
+	}
+	message.Text = text
+
+	decision, err := s.moderator.Moderate(ctx, moderation.Message{
+		ChatID: message.ChatID,
+		Sender: message.Sender,
+		Text:   message.Text,
+	})
+	if err != nil {
+		s.log.Error("failed to moderate message", sl.OpErr(op, err))
+		return dto.Message{}, moderation.Decision{}, err
+	}
+	metrics.ModerationDecisions.WithLabelValues(string(decision.Action), decision.Reason).Inc()
+
+	switch decision.Action {
+	case moderation.Reject:
+		s.log.Info("message rejected by moderation",
+			slog.Int64("sender", message.Sender),
+			slog.String("reason", decision.Reason),
+		)
+		return dto.Message{}, decision, &moderation.RejectedError{Reason: decision.Reason}
+	case moderation.Flag:
+		message.FlagReason = decision.Reason
+	}
+	message.Text = decision.Text
+
+	return message, decision, nil
+}
+
+// send screens the message and stores it within tx as the chat's last
+// message. The sender is checked before screening, so attempts that are
+// refused anyway do not count towards the spam filter's history. Messages in
+// chats with a TTL expire after it.
+func (s *MessageService) send(ctx context.Context, tx pgx.Tx, message dto.Message) (models.Message, moderation.Decision, error) {
+	const op = "message.service.send"
+
+	chat, err := s.checkSender(ctx, tx, message)
+	if err != nil {
+		return models.Message{}, moderation.Decision{}, err
+	}
+
+	message, decision, err := s.screen(ctx, message)
+	if err != nil {
+		return models.Message{}, decision, err
+	}
+	if chat.MessageTTL > 0 {
+		expiresAt := message.CreatedAt.Add(time.Duration(chat.MessageTTL) * time.Second)
+		message.ExpiresAt = &expiresAt
+	}
+
+	messageID, err := s.messagesDB.CreateMessage(ctx, tx, message)
+	if err != nil {
+		s.log.Error("failed to create message", sl.OpErr(op, err))
+		return models.Message{}, moderation.Decision{}, err
+	}
+	if messageID == 0 {
+		err = errors.New("message id is empty")
+		s.log.Error("failed to create message", sl.OpErr(op, err))
+		return models.Message{}, moderation.Decision{}, err
+	}
+
+	if message.FlagReason != "" {
+		_, err = s.reportDB.CreateReport(ctx, tx, dto.Report{
+			ChatID:    message.ChatID,
+			MessageID: messageID,
+			Message:   &message,
+			Reason:    "flagged by moderation: " + message.FlagReason,
+			CreatedAt: message.CreatedAt,
+		})
+		if err != nil {
+			s.log.Error("failed to report flagged message", sl.OpErr(op, err))
+			return models.Message{}, moderation.Decision{}, err
+		}
+	}
+
+	if err = s.chatDB.UpdateChatMessage(ctx, tx, message.ChatID, message.Text, message.CreatedAt); err != nil {
+		s.log.Error("failed to update chat message", sl.OpErr(op, err))
+		return models.Message{}, moderation.Decision{}, err
 	}
+
+	// A new message brings archived chats back unless they are muted.
+	if err = s.chatDB.UnarchiveChat(ctx, tx, message.ChatID, message.CreatedAt); err != nil {
+		s.log.Error("failed to unarchive chat", sl.OpErr(op, err))
+		return models.Message{}, moderation.Decision{}, err
+	}
+
+	return models.Message{
+		ID:        messageID,
+		ChatID:    message.ChatID,
+		Sender:    message.Sender,
+		Text:      message.Text,
+		CreatedAt: message.CreatedAt,
+		ExpiresAt: message.ExpiresAt,
+	}, decision, nil
 }
 
 func (s *MessageService) CreateMessage(ctx context.Context, message dto.Message) (messageID int64, err error) {
@@ -66,6 +274,101 @@ func (s *MessageService) CreateMessage(ctx context.Context, message dto.Message)
 	return messageID, nil
 }
 
+// CheckMember reports whether the user is a member of the chat and so may
+// read its messages.
+func (s *MessageService) CheckMember(ctx context.Context, userID int64, chatID int64) error {
+	const op = "message.service.CheckMember"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = s.chatDB.GetChatByID(ctx, tx, chatID); err != nil {
+		s.log.Error("failed to get chat", sl.OpErr(op, err))
+		return err
+	}
+	if _, err = s.chatDB.GetMember(ctx, tx, chatID, userID); err != nil {
+		if errors.Is(err, chatStorage.ErrMemberNotFound) {
+			return chatService.ErrNotChatMember
+		}
+		s.log.Error("failed to get chat member", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+// CheckSender reports whether the user may post to the chat right now, so
+// callers can reject outsiders before charging the chat's rate limit.
+func (s *MessageService) CheckSender(ctx context.Context, sender int64, chatID int64) error {
+	const op = "message.service.CheckSender"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = s.checkSender(ctx, tx, dto.Message{
+		ChatID:    chatID,
+		Sender:    sender,
+		CreatedAt: time.Now().UTC(),
+	})
+	return err
+}
+
+// checkSender makes sure the sender may post to the chat and returns the
+// chat.
+func (s *MessageService) checkSender(ctx context.Context, tx pgx.Tx, message dto.Message) (models.Chat, error) {
+	const op = "message.service.checkSender"
+
+	chat, err := s.chatDB.GetChatByID(ctx, tx, message.ChatID)
+	if err != nil {
+		s.log.Error("failed to get chat", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+	member, err := s.chatDB.GetMember(ctx, tx, message.ChatID, message.Sender)
+	if err != nil {
+		if errors.Is(err, chatStorage.ErrMemberNotFound) {
+			return models.Chat{}, chatService.ErrNotChatMember
+		}
+		s.log.Error("failed to get chat member", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+	// Channel subscribers only read.
+	if chat.Type == models.ChatChannel && !member.CanManage() {
+		return models.Chat{}, chatService.ErrNotAllowed
+	}
+
+	suspended, err := s.userDB.IsSuspended(ctx, tx, message.Sender, message.CreatedAt)
+	if err != nil {
+		s.log.Error("failed to check sender suspension", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+	if suspended {
+		return models.Chat{}, ErrSenderSuspended
+	}
+
+	if chat.Type != models.ChatDirect {
+		return chat, nil
+	}
+
+	blocked, err := s.userDB.IsBlocked(ctx, tx, chat.Other(message.Sender), message.Sender)
+	if err != nil {
+		s.log.Error("failed to check block", sl.OpErr(op, err))
+		return models.Chat{}, err
+	}
+	if blocked {
+		return models.Chat{}, chatService.ErrBlocked
+	}
+
+	return chat, nil
+}
+
 func (s *MessageService) GetMessageByID(ctx context.Context, messageID int64) (models.Message, error) {
 	const op = "message.service.GetMessageByID"
 
@@ -76,7 +379,7 @@ func (s *MessageService) GetMessageByID(ctx context.Context, messageID int64) (m
 	}
 	defer tx.Rollback(ctx)
 
-	message, err := s.messagesDB.GetMessageByID(ctx, tx, messageID)
+	message, err := s.messagesDB.GetMessageByID(ctx, tx, messageID, time.Now().UTC())
 	if err != nil {
 		s.log.Error("failed to get message", sl.OpErr(op, err))
 		return models.Message{}, err
@@ -100,7 +403,7 @@ func (s *MessageService) GetMessagesByChatID(ctx context.Context, chatID int64,
 	}
 	defer tx.Rollback(ctx)
 
-	messages, err := s.messagesDB.GetMessagesByChatID(ctx, tx, chatID, limit, offset)
+	messages, err := s.messagesDB.GetMessagesByChatID(ctx, tx, chatID, time.Now().UTC(), limit, offset)
 	if err != nil {
 		s.log.Error("failed to get messages", sl.OpErr(op, err))
 		return nil, err
@@ -0,0 +1,256 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"simple-chat/internal/lib/moderation"
+	chatService "simple-chat/internal/services/chat"
+	chatStorage "simple-chat/internal/storage/chat"
+	scheduledStorage "simple-chat/internal/storage/scheduled"
+	"simple-chat/internal/validator"
+	"time"
+)
+
+// ScheduleMessage queues a message for delivery at sendAt. The text and the
+// sender's right to post are checked now and again on delivery.
+func (s *MessageService) ScheduleMessage(ctx context.Context, message dto.Message, sendAt time.Time) (scheduled models.ScheduledMessage, err error) {
+	const op = "message.service.ScheduleMessage"
+
+	if _, err = s.processor.Process(message.Text); err != nil {
+		s.log.Debug("message rejected by processing pipeline", sl.OpErr(op, err))
+		return models.ScheduledMessage{}, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.ScheduledMessage{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			scheduled = models.ScheduledMessage{}
+		}
+	}()
+
+	if _, err = s.checkSender(ctx, tx, message); err != nil {
+		return models.ScheduledMessage{}, err
+	}
+
+	scheduled = models.ScheduledMessage{
+		ChatID:    message.ChatID,
+		Sender:    message.Sender,
+		Text:      message.Text,
+		SendAt:    sendAt,
+		Status:    models.ScheduledPending,
+		CreatedAt: message.CreatedAt,
+	}
+	scheduled.ID, err = s.scheduledDB.CreateScheduled(ctx, tx, scheduled)
+	if err != nil {
+		s.log.Error("failed to create scheduled message", sl.OpErr(op, err))
+		return models.ScheduledMessage{}, err
+	}
+
+	return scheduled, nil
+}
+
+func (s *MessageService) GetScheduledMessages(ctx context.Context, sender int64) ([]models.ScheduledMessage, error) {
+	const op = "message.service.GetScheduledMessages"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	messages, err := s.scheduledDB.GetUserScheduled(ctx, tx, sender)
+	if err != nil {
+		s.log.Error("failed to get scheduled messages", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// UpdateScheduledMessage changes the text or time of one of the sender's
+// scheduled messages.
+func (s *MessageService) UpdateScheduledMessage(ctx context.Context, sender int64, messageID int64, req dto.UpdateScheduledRequest) (scheduled models.ScheduledMessage, err error) {
+	const op = "message.service.UpdateScheduledMessage"
+
+	if req.Text != nil {
+		if _, err = s.processor.Process(*req.Text); err != nil {
+			s.log.Debug("message rejected by processing pipeline", sl.OpErr(op, err))
+			return models.ScheduledMessage{}, err
+		}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.ScheduledMessage{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			scheduled = models.ScheduledMessage{}
+		}
+	}()
+
+	scheduled, err = s.scheduledDB.UpdateScheduled(ctx, tx, messageID, sender, req.Text, req.SendAt)
+	if err != nil {
+		s.log.Error("failed to update scheduled message", sl.OpErr(op, err))
+		return models.ScheduledMessage{}, err
+	}
+
+	return scheduled, nil
+}
+
+func (s *MessageService) CancelScheduledMessage(ctx context.Context, sender int64, messageID int64) (err error) {
+	const op = "message.service.CancelScheduledMessage"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+		}
+	}()
+
+	if err = s.scheduledDB.DeleteScheduled(ctx, tx, messageID, sender); err != nil {
+		s.log.Error("failed to delete scheduled message", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+// DeliverScheduled sends up to limit messages that are due at at through
+// the regular send path and returns the messages that were sent. Each
+// message is delivered in its own transaction that keeps it locked, so
+// several instances can deliver concurrently without sending twice.
+func (s *MessageService) DeliverScheduled(ctx context.Context, at time.Time, limit int) ([]models.Message, error) {
+	var sent []models.Message
+	for range limit {
+		message, ok, err := s.deliverNext(ctx, at)
+		if err != nil {
+			return sent, err
+		}
+		if !ok {
+			break
+		}
+		if message.ID != 0 {
+			sent = append(sent, message)
+		}
+	}
+
+	return sent, nil
+}
+
+// deliverNext delivers the next due message. It reports false when no
+// message is due. Messages that can no longer be sent are marked as failed
+// and return a zero message.
+func (s *MessageService) deliverNext(ctx context.Context, at time.Time) (sent models.Message, ok bool, err error) {
+	const op = "message.service.deliverNext"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return models.Message{}, false, err
+	}
+	defer func() {
+		if err != nil || !ok {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			sent, ok = models.Message{}, false
+		}
+	}()
+
+	scheduled, err := s.scheduledDB.GetDueScheduled(ctx, tx, at)
+	if errors.Is(err, scheduledStorage.ErrScheduledNotFound) {
+		return models.Message{}, false, nil
+	}
+	if err != nil {
+		s.log.Error("failed to get due scheduled message", sl.OpErr(op, err))
+		return models.Message{}, false, err
+	}
+
+	// The savepoint keeps the lock when the send fails.
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to create savepoint", sl.OpErr(op, err))
+		return models.Message{}, false, err
+	}
+	sent, _, err = s.send(ctx, sp, dto.Message{
+		ChatID:    scheduled.ChatID,
+		Sender:    scheduled.Sender,
+		Text:      scheduled.Text,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		sp.Rollback(ctx)
+	} else if err = sp.Commit(ctx); err != nil {
+		s.log.Error("failed to release savepoint", sl.OpErr(op, err))
+		return models.Message{}, false, err
+	}
+	if err != nil {
+		if !undeliverable(err) {
+			return models.Message{}, false, err
+		}
+		s.log.Info("scheduled message could not be delivered",
+			slog.Int64("scheduled_id", scheduled.ID),
+			sl.OpErr(op, err),
+		)
+		if err = s.scheduledDB.FailScheduled(ctx, tx, scheduled.ID, err.Error()); err != nil {
+			s.log.Error("failed to mark scheduled message as failed", sl.OpErr(op, err))
+			return models.Message{}, false, err
+		}
+		return models.Message{}, true, nil
+	}
+
+	if err = s.scheduledDB.DeleteScheduled(ctx, tx, scheduled.ID, scheduled.Sender); err != nil {
+		s.log.Error("failed to delete scheduled message", sl.OpErr(op, err))
+		return models.Message{}, false, err
+	}
+
+	return sent, true, nil
+}
+
+// undeliverable reports whether a scheduled message failed for good, as
+// opposed to failing for a reason that retrying may fix.
+func undeliverable(err error) bool {
+	var (
+		fields   validator.Errors
+		rejected *moderation.RejectedError
+	)
+
+	return errors.As(err, &fields) ||
+		errors.As(err, &rejected) ||
+		errors.Is(err, ErrSenderSuspended) ||
+		errors.Is(err, chatService.ErrNotChatMember) ||
+		errors.Is(err, chatService.ErrBlocked) ||
+		errors.Is(err, chatService.ErrNotAllowed) ||
+		errors.Is(err, chatStorage.ErrChatNotFound)
+}
@@ -0,0 +1,69 @@
+package message
+
+import (
+	"context"
+	"log/slog"
+	"simple-chat/internal/config"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"time"
+)
+
+// Broadcaster pushes delivered messages to the chat's connected clients.
+type Broadcaster interface {
+	BroadcastMessage(message models.Message)
+}
+
+// Scheduler periodically delivers due scheduled messages. Every instance of
+// simple-chat runs one; row locking keeps them from delivering a message
+// twice.
+type Scheduler struct {
+	log         *slog.Logger
+	messages    *MessageService
+	broadcaster Broadcaster
+	cfg         config.Scheduler
+}
+
+func NewScheduler(log *slog.Logger, messages *MessageService, broadcaster Broadcaster, cfg config.Scheduler) *Scheduler {
+	return &Scheduler{
+		log:         log,
+		messages:    messages,
+		broadcaster: broadcaster,
+		cfg:         cfg,
+	}
+}
+
+// Run delivers due messages every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	const op = "message.service.Scheduler.Run"
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// A full batch may mean more messages are due, so keep going until
+		// the backlog is drained.
+		for {
+			sent, err := s.messages.DeliverScheduled(ctx, time.Now().UTC(), s.cfg.BatchSize)
+			for _, message := range sent {
+				s.broadcaster.BroadcastMessage(message)
+			}
+			if len(sent) > 0 {
+				s.log.Debug("scheduled messages delivered", slog.Int("count", len(sent)))
+			}
+			if err != nil {
+				s.log.Error("failed to deliver scheduled messages", sl.OpErr(op, err))
+				break
+			}
+			if len(sent) < s.cfg.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
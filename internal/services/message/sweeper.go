@@ -0,0 +1,72 @@
+package message
+
+import (
+	"context"
+	"log/slog"
+	"simple-chat/internal/config"
+	"simple-chat/internal/lib/logger/sl"
+	"time"
+)
+
+// DeletionBroadcaster tells a chat's connected clients which messages were
+// deleted.
+type DeletionBroadcaster interface {
+	BroadcastDeleted(chatID int64, messageIDs []int64)
+}
+
+// Sweeper periodically purges expired messages in batches. Like the
+// Scheduler, it runs on every instance.
+type Sweeper struct {
+	log         *slog.Logger
+	messages    *MessageService
+	broadcaster DeletionBroadcaster
+	cfg         config.Sweeper
+}
+
+func NewSweeper(log *slog.Logger, messages *MessageService, broadcaster DeletionBroadcaster, cfg config.Sweeper) *Sweeper {
+	return &Sweeper{
+		log:         log,
+		messages:    messages,
+		broadcaster: broadcaster,
+		cfg:         cfg,
+	}
+}
+
+// Run purges expired messages every interval until ctx is done.
+func (s *Sweeper) Run(ctx context.Context) {
+	const op = "message.service.Sweeper.Run"
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			purged, err := s.messages.PurgeExpired(ctx, time.Now().UTC(), s.cfg.BatchSize)
+			if err != nil {
+				s.log.Error("failed to purge expired messages", sl.OpErr(op, err))
+				break
+			}
+
+			deleted := make(map[int64][]int64)
+			for _, message := range purged {
+				deleted[message.ChatID] = append(deleted[message.ChatID], message.ID)
+			}
+			for chatID, messageIDs := range deleted {
+				s.broadcaster.BroadcastDeleted(chatID, messageIDs)
+			}
+			if len(purged) > 0 {
+				s.log.Debug("expired messages purged", slog.Int("count", len(purged)))
+			}
+
+			if len(purged) < s.cfg.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	chatService "simple-chat/internal/services/chat"
+	chatStorage "simple-chat/internal/storage/chat"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReportService handles abuse reports from users and the admin review queue.
+type ReportService struct {
+	log        *slog.Logger
+	reportDB   ReportDB
+	chatDB     ChatDB
+	messagesDB MessagesDB
+	pool       *pgxpool.Pool
+}
+
+type ReportDB interface {
+	CreateReport(ctx context.Context, tx pgx.Tx, report dto.Report) (int64, error)
+	GetOpenReports(ctx context.Context, tx pgx.Tx, limit int, offset int) ([]models.Report, error)
+	ResolveReport(ctx context.Context, tx pgx.Tx, reportID int64, status string, resolution string, resolvedBy int64, resolvedAt time.Time) error
+	ResolveMessageReports(ctx context.Context, tx pgx.Tx, messageID int64, resolution string, resolvedBy int64, resolvedAt time.Time) error
+}
+
+type ChatDB interface {
+	GetChatByID(ctx context.Context, tx pgx.Tx, chatID int64) (models.Chat, error)
+	GetMember(ctx context.Context, tx pgx.Tx, chatID int64, userID int64) (models.ChatMember, error)
+	RefreshChatMessage(ctx context.Context, tx pgx.Tx, chatIDs []int64) error
+}
+
+type MessagesDB interface {
+	GetMessageByID(ctx context.Context, tx pgx.Tx, messageID int64, at time.Time) (models.Message, error)
+	DeleteMessage(ctx context.Context, tx pgx.Tx, messageID int64) error
+}
+
+func NewReportService(log *slog.Logger, reportDB ReportDB, chatDB ChatDB, messagesDB MessagesDB, pool *pgxpool.Pool) *ReportService {
+	return &ReportService{
+		log:        log,
+		reportDB:   reportDB,
+		chatDB:     chatDB,
+		messagesDB: messagesDB,
+		pool:       pool,
+	}
+}
+
+// CreateReport files a report about a message or, when no message is given,
+// a whole chat. Only members of the chat may report it.
+func (s *ReportService) CreateReport(ctx context.Context, reporter int64, req dto.ReportRequest) (reportID int64, err error) {
+	const op = "report.service.CreateReport"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	report := dto.Report{
+		Reporter:  reporter,
+		ChatID:    req.ChatID,
+		MessageID: req.MessageID,
+		Reason:    req.Reason,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if req.MessageID != 0 {
+		message, err := s.messagesDB.GetMessageByID(ctx, tx, req.MessageID, report.CreatedAt)
+		if err != nil {
+			s.log.Error("failed to get reported message", sl.OpErr(op, err))
+			return 0, err
+		}
+		report.ChatID = message.ChatID
+		report.Message = &dto.Message{
+			ChatID:    message.ChatID,
+			Sender:    message.Sender,
+			Text:      message.Text,
+			CreatedAt: message.CreatedAt,
+		}
+	}
+
+	if _, err = s.chatDB.GetChatByID(ctx, tx, report.ChatID); err != nil {
+		s.log.Error("failed to get reported chat", sl.OpErr(op, err))
+		return 0, err
+	}
+	if _, err = s.chatDB.GetMember(ctx, tx, report.ChatID, reporter); err != nil {
+		if errors.Is(err, chatStorage.ErrMemberNotFound) {
+			err = chatService.ErrNotChatMember
+		}
+		return 0, err
+	}
+
+	if err = report.Validate(); err != nil {
+		s.log.Error("failed to validate report", sl.OpErr(op, err))
+		return 0, err
+	}
+
+	reportID, err = s.reportDB.CreateReport(ctx, tx, report)
+	if err != nil {
+		s.log.Error("failed to create report", sl.OpErr(op, err))
+		return 0, err
+	}
+
+	return reportID, nil
+}
+
+func (s *ReportService) GetOpenReports(ctx context.Context, limit int, offset int) ([]models.Report, error) {
+	const op = "report.service.GetOpenReports"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	reports, err := s.reportDB.GetOpenReports(ctx, tx, limit, offset)
+	if err != nil {
+		s.log.Error("failed to get open reports", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+func (s *ReportService) ResolveReport(ctx context.Context, reportID int64, adminID int64, req dto.ResolveReportRequest) (err error) {
+	const op = "report.service.ResolveReport"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	err = s.reportDB.ResolveReport(ctx, tx, reportID, req.Status, req.Resolution, adminID, time.Now().UTC())
+	if err != nil {
+		s.log.Error("failed to resolve report", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+// DeleteMessage removes a message, resolves the open reports about it and
+// refreshes the chat's last message. It returns the chat the message was in.
+func (s *ReportService) DeleteMessage(ctx context.Context, messageID int64, adminID int64) (chatID int64, err error) {
+	const op = "report.service.DeleteMessage"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			chatID = 0
+		}
+	}()
+
+	message, err := s.messagesDB.GetMessageByID(ctx, tx, messageID, time.Now().UTC())
+	if err != nil {
+		s.log.Error("failed to get message", sl.OpErr(op, err))
+		return 0, err
+	}
+
+	// Reports lose their message reference once it is deleted, so they are
+	// resolved first.
+	err = s.reportDB.ResolveMessageReports(ctx, tx, messageID, "message deleted", adminID, time.Now().UTC())
+	if err != nil {
+		s.log.Error("failed to resolve message reports", sl.OpErr(op, err))
+		return 0, err
+	}
+
+	if err = s.messagesDB.DeleteMessage(ctx, tx, messageID); err != nil {
+		s.log.Error("failed to delete message", sl.OpErr(op, err))
+		return 0, err
+	}
+
+	if err = s.chatDB.RefreshChatMessage(ctx, tx, []int64{message.ChatID}); err != nil {
+		s.log.Error("failed to refresh chat message", sl.OpErr(op, err))
+		return 0, err
+	}
+
+	return message.ChatID, nil
+}
@@ -0,0 +1,228 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrBlockSelf = errors.New("users cannot block themselves")
+
+// UserService is a local directory of users that have authenticated against
+// the chat at least once. It supplies profiles and resolves user IDs when SSO
+// cannot answer.
+type UserService struct {
+	log    *slog.Logger
+	userDB UserDB
+	pool   *pgxpool.Pool
+}
+
+type UserDB interface {
+	SaveUser(ctx context.Context, tx pgx.Tx, user models.User, updatedAt time.Time) error
+	GetUsers(ctx context.Context, tx pgx.Tx, userIDs []int64) ([]models.User, error)
+	SuspendUser(ctx context.Context, tx pgx.Tx, suspension models.Suspension) error
+	LiftSuspension(ctx context.Context, tx pgx.Tx, userID int64) error
+	BlockUser(ctx context.Context, tx pgx.Tx, blocker int64, blocked int64, createdAt time.Time) error
+	UnblockUser(ctx context.Context, tx pgx.Tx, blocker int64, blocked int64) error
+	GetBlockedUsers(ctx context.Context, tx pgx.Tx, blocker int64) ([]models.Block, error)
+}
+
+func NewUserService(log *slog.Logger, userDB UserDB, pool *pgxpool.Pool) *UserService {
+	return &UserService{
+		log:    log,
+		userDB: userDB,
+		pool:   pool,
+	}
+}
+
+func (s *UserService) SaveUser(ctx context.Context, user models.User) (err error) {
+	const op = "user.service.SaveUser"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	if err = s.userDB.SaveUser(ctx, tx, user, time.Now().UTC()); err != nil {
+		s.log.Error("failed to save user", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *UserService) GetUsers(ctx context.Context, userIDs []int64) (map[int64]models.User, error) {
+	const op = "user.service.GetUsers"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	users, err := s.userDB.GetUsers(ctx, tx, userIDs)
+	if err != nil {
+		s.log.Error("failed to get users", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	result := make(map[int64]models.User, len(users))
+	for _, user := range users {
+		result[user.UserID] = user
+	}
+
+	return result, nil
+}
+
+// SuspendUser stops the user from sending messages until suspension.Until,
+// or indefinitely when it is nil.
+func (s *UserService) SuspendUser(ctx context.Context, suspension models.Suspension) (err error) {
+	const op = "user.service.SuspendUser"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	if err = s.userDB.SuspendUser(ctx, tx, suspension); err != nil {
+		s.log.Error("failed to suspend user", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *UserService) LiftSuspension(ctx context.Context, userID int64) (err error) {
+	const op = "user.service.LiftSuspension"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	if err = s.userDB.LiftSuspension(ctx, tx, userID); err != nil {
+		s.log.Error("failed to lift suspension", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+// BlockUser stops blocked from creating chats with blocker and from sending
+// messages to their existing chats.
+func (s *UserService) BlockUser(ctx context.Context, blocker int64, blocked int64) (err error) {
+	const op = "user.service.BlockUser"
+
+	if blocker == blocked {
+		return ErrBlockSelf
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	if err = s.userDB.BlockUser(ctx, tx, blocker, blocked, time.Now().UTC()); err != nil {
+		s.log.Error("failed to block user", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *UserService) UnblockUser(ctx context.Context, blocker int64, blocked int64) (err error) {
+	const op = "user.service.UnblockUser"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			s.log.Error("failed to commit transaction", sl.OpErr(op, err))
+			return
+		}
+	}()
+
+	if err = s.userDB.UnblockUser(ctx, tx, blocker, blocked); err != nil {
+		s.log.Error("failed to unblock user", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *UserService) GetBlockedUsers(ctx context.Context, blocker int64) ([]models.Block, error) {
+	const op = "user.service.GetBlockedUsers"
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to start transaction", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	blocks, err := s.userDB.GetBlockedUsers(ctx, tx, blocker)
+	if err != nil {
+		s.log.Error("failed to get blocked users", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return blocks, nil
+}
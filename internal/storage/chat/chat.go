@@ -24,12 +24,20 @@ func NewChatDB(log *slog.Logger) *ChatDB {
 }
 
 const (
-	chatTable = "chat"
+	chatTable     = "chat"
+	blockTable    = "user_block"
+	settingsTable = "chat_settings"
+	memberTable   = "chat_member"
+	messageTable  = "message"
+
+	uniqueViolation = "23505"
 )
 
 var (
-	ErrChatNotFound  = fmt.Errorf("chat not found")
-	ErrChatsNotFound = fmt.Errorf("chats not found")
+	ErrChatNotFound   = fmt.Errorf("chat not found")
+	ErrChatsNotFound  = fmt.Errorf("chats not found")
+	ErrMemberNotFound = fmt.Errorf("chat member not found")
+	ErrAlreadyMember  = fmt.Errorf("user is already a member of the chat")
 )
 
 func (c *ChatDB) CreateChat(ctx context.Context, tx pgx.Tx, chat *dto.Chat) (int64, error) {
@@ -37,8 +45,8 @@ func (c *ChatDB) CreateChat(ctx context.Context, tx pgx.Tx, chat *dto.Chat) (int
 
 	q := fmt.Sprintf(`
 		INSERT INTO %s 
-			(first_user_id, second_user_id, updated_at) 
-		VALUES ($1, $2, $3)
+			(type, first_user_id, second_user_id, title, description, updated_at) 
+		VALUES (COALESCE(NULLIF($1, ''), 'direct'), NULLIF($2, 0), NULLIF($3, 0), NULLIF($4, ''), NULLIF($5, ''), $6)
 		RETURNING id;
 	`, chatTable)
 
@@ -46,7 +54,7 @@ func (c *ChatDB) CreateChat(ctx context.Context, tx pgx.Tx, chat *dto.Chat) (int
 
 	var chatID int64
 
-	err := tx.QueryRow(ctx, q, chat.FirstUserID, chat.SecondUserID, chat.UpdatedAt).Scan(&chatID)
+	err := tx.QueryRow(ctx, q, chat.Type, chat.FirstUserID, chat.SecondUserID, chat.Title, chat.Description, chat.UpdatedAt).Scan(&chatID)
 	if err != nil {
 		c.log.Error("faield to create chat", sl.OpErr(op, err))
 		return 0, err
@@ -73,11 +81,94 @@ func (c *ChatDB) UpdateChatMessage(ctx context.Context, tx pgx.Tx, chatID int64,
 
 	return nil
 }
+
+// UpdateChatInfo changes the chat's title, description and avatar, leaving
+// nil fields as they are.
+func (c *ChatDB) UpdateChatInfo(ctx context.Context, tx pgx.Tx, chatID int64, info dto.ChatInfoRequest) error {
+	const op = "storage.chat.UpdateChatInfo"
+
+	q := fmt.Sprintf(`
+        UPDATE %s
+        SET title = COALESCE($2, title),
+            description = COALESCE($3, description),
+            avatar_url = COALESCE($4, avatar_url)
+        WHERE id = $1;
+	`, chatTable)
+
+	c.log.Debug("update chat info query:", slog.String("query", query.QueryToString(q)))
+
+	tag, err := tx.Exec(ctx, q, chatID, info.Title, info.Description, info.AvatarURL)
+	if err != nil {
+		c.log.Error("faield to update chat info", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrChatNotFound
+	}
+
+	return nil
+}
+
+// SetMessageTTL sets how many seconds new messages stay in the chat; zero
+// keeps them forever.
+func (c *ChatDB) SetMessageTTL(ctx context.Context, tx pgx.Tx, chatID int64, ttl int) error {
+	const op = "storage.chat.SetMessageTTL"
+
+	q := fmt.Sprintf(`
+        UPDATE %s
+        SET message_ttl = NULLIF($2, 0)
+        WHERE id = $1;
+	`, chatTable)
+
+	c.log.Debug("set message ttl query:", slog.String("query", query.QueryToString(q)))
+
+	tag, err := tx.Exec(ctx, q, chatID, ttl)
+	if err != nil {
+		c.log.Error("faield to set message ttl", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrChatNotFound
+	}
+
+	return nil
+}
+
+// RefreshChatMessage sets the last message of the chats to their newest
+// remaining message, such as after older ones expired.
+func (c *ChatDB) RefreshChatMessage(ctx context.Context, tx pgx.Tx, chatIDs []int64) error {
+	const op = "storage.chat.RefreshChatMessage"
+
+	q := fmt.Sprintf(`
+        UPDATE %s c
+        SET last_message = (
+            SELECT m.text FROM %s m
+            WHERE m.chat_id = c.id
+            ORDER BY m.created_at DESC, m.id DESC
+            LIMIT 1
+        )
+        WHERE c.id = ANY($1);
+	`, chatTable, messageTable)
+
+	c.log.Debug("refresh chat message query:", slog.String("query", query.QueryToString(q)))
+
+	if _, err := tx.Exec(ctx, q, chatIDs); err != nil {
+		c.log.Error("faield to refresh chat message", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
 func (c *ChatDB) GetChatByID(ctx context.Context, tx pgx.Tx, chatID int64) (models.Chat, error) {
 	const op = "storage.chat.GetChatByID"
 
 	q := fmt.Sprintf(`
-        SELECT id, first_user_id, second_user_id, COALESCE(last_message, '') AS last_message, updated_at
+        SELECT id, type, COALESCE(first_user_id, 0), COALESCE(second_user_id, 0),
+            COALESCE(title, ''), COALESCE(description, ''), COALESCE(avatar_url, ''),
+            COALESCE(message_ttl, 0),
+            COALESCE(last_message, '') AS last_message, updated_at,
+            CASE WHEN type = 'channel' THEN member_count END
         FROM %s
         WHERE id = $1;
     `, chatTable)
@@ -86,7 +177,13 @@ func (c *ChatDB) GetChatByID(ctx context.Context, tx pgx.Tx, chatID int64) (mode
 
 	var chat models.Chat
 
-	err := tx.QueryRow(ctx, q, chatID).Scan(&chat.ID, &chat.FirstUserID, &chat.SecondUserID, &chat.LastMessage, &chat.UpdatedAt)
+	err := tx.QueryRow(ctx, q, chatID).Scan(
+		&chat.ID, &chat.Type, &chat.FirstUserID, &chat.SecondUserID,
+		&chat.Title, &chat.Description, &chat.AvatarURL,
+		&chat.MessageTTL,
+		&chat.LastMessage, &chat.UpdatedAt,
+		&chat.SubscriberCount,
+	)
 
 	c.log.Debug("chat by id:",
 		slog.String("op", op),
@@ -104,21 +201,37 @@ func (c *ChatDB) GetChatByID(ctx context.Context, tx pgx.Tx, chatID int64) (mode
 
 	return chat, nil
 }
-func (c *ChatDB) GetUserChats(ctx context.Context, tx pgx.Tx, userID int64, limit int, offset int) ([]models.Chat, error) {
+
+// GetUserChats returns the user's chats with the user's settings, pinned
+// chats first.
+func (c *ChatDB) GetUserChats(ctx context.Context, tx pgx.Tx, userID int64, filter dto.ChatFilter, limit int, offset int) ([]models.Chat, error) {
 	const op = "storage.chat.GetUserChats"
 
 	q := fmt.Sprintf(`
-        SELECT id, first_user_id, second_user_id, COALESCE(last_message, '') AS last_message, updated_at
-        FROM %s
-        WHERE first_user_id = $1 OR second_user_id = $1
-        LIMIT $2 OFFSET $3;
-	`, chatTable)
+        SELECT c.id, c.type, COALESCE(c.first_user_id, 0), COALESCE(c.second_user_id, 0),
+            COALESCE(c.title, ''), COALESCE(c.description, ''), COALESCE(c.avatar_url, ''),
+            COALESCE(c.message_ttl, 0),
+            COALESCE(c.last_message, '') AS last_message, c.updated_at,
+            CASE WHEN c.type = 'channel' THEN c.member_count END,
+            s.muted_until, COALESCE(s.archived, FALSE), s.pinned_order
+        FROM %s c
+        JOIN %s m ON m.chat_id = c.id AND m.user_id = $1
+        LEFT JOIN %s s ON s.chat_id = c.id AND s.user_id = $1
+        WHERE (NOT $2 OR c.type <> 'direct' OR NOT EXISTS (
+                SELECT 1 FROM %s b
+                WHERE b.blocker = $1
+                    AND b.blocked = CASE WHEN c.first_user_id = $1 THEN c.second_user_id ELSE c.first_user_id END
+            ))
+            AND ($3::BOOLEAN IS NULL OR COALESCE(s.archived, FALSE) = $3)
+        ORDER BY s.pinned_order ASC NULLS LAST, c.updated_at DESC
+        LIMIT $4 OFFSET $5;
+	`, chatTable, memberTable, settingsTable, blockTable)
 
 	c.log.Debug("get user chats query:", slog.String("query", query.QueryToString(q)))
 
 	var chats []models.Chat
 
-	rows, err := tx.Query(ctx, q, userID, limit, offset)
+	rows, err := tx.Query(ctx, q, userID, filter.HideBlocked, filter.Archived, limit, offset)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, ErrChatsNotFound
@@ -128,13 +241,24 @@ func (c *ChatDB) GetUserChats(ctx context.Context, tx pgx.Tx, userID int64, limi
 	}
 
 	for rows.Next() {
-		var chat models.Chat
-
-		err := rows.Scan(&chat.ID, &chat.FirstUserID, &chat.SecondUserID, &chat.LastMessage, &chat.UpdatedAt)
+		var (
+			chat     models.Chat
+			settings models.ChatSettings
+		)
+
+		err := rows.Scan(
+			&chat.ID, &chat.Type, &chat.FirstUserID, &chat.SecondUserID,
+			&chat.Title, &chat.Description, &chat.AvatarURL,
+			&chat.MessageTTL,
+			&chat.LastMessage, &chat.UpdatedAt,
+			&chat.SubscriberCount,
+			&settings.MutedUntil, &settings.Archived, &settings.PinnedOrder,
+		)
 		if err != nil {
 			c.log.Error("faield to get user chats", sl.OpErr(op, err))
 			return nil, err
 		}
+		chat.Settings = &settings
 
 		chats = append(chats, chat)
 	}
@@ -146,3 +270,78 @@ func (c *ChatDB) GetUserChats(ctx context.Context, tx pgx.Tx, userID int64, limi
 
 	return chats, nil
 }
+
+// GetChatSettings returns the user's settings for the chat, or the defaults
+// when the user never changed them.
+func (c *ChatDB) GetChatSettings(ctx context.Context, tx pgx.Tx, chatID int64, userID int64) (models.ChatSettings, error) {
+	const op = "storage.chat.GetChatSettings"
+
+	q := fmt.Sprintf(`
+        SELECT muted_until, archived, pinned_order
+        FROM %s
+        WHERE chat_id = $1 AND user_id = $2;
+	`, settingsTable)
+
+	c.log.Debug("get chat settings query:", slog.String("query", query.QueryToString(q)))
+
+	var settings models.ChatSettings
+
+	err := tx.QueryRow(ctx, q, chatID, userID).Scan(&settings.MutedUntil, &settings.Archived, &settings.PinnedOrder)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.ChatSettings{}, nil
+		}
+		c.log.Error("faield to get chat settings", sl.OpErr(op, err))
+		return models.ChatSettings{}, err
+	}
+
+	return settings, nil
+}
+
+func (c *ChatDB) SaveChatSettings(ctx context.Context, tx pgx.Tx, chatID int64, userID int64, settings models.ChatSettings, updatedAt time.Time) error {
+	const op = "storage.chat.SaveChatSettings"
+
+	q := fmt.Sprintf(`
+        INSERT INTO %s
+            (chat_id, user_id, muted_until, archived, pinned_order, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (chat_id, user_id) DO UPDATE
+        SET muted_until = EXCLUDED.muted_until,
+            archived = EXCLUDED.archived,
+            pinned_order = EXCLUDED.pinned_order,
+            updated_at = EXCLUDED.updated_at;
+	`, settingsTable)
+
+	c.log.Debug("save chat settings query:", slog.String("query", query.QueryToString(q)))
+
+	_, err := tx.Exec(ctx, q, chatID, userID, settings.MutedUntil, settings.Archived, settings.PinnedOrder, updatedAt)
+	if err != nil {
+		c.log.Error("faield to save chat settings", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+// UnarchiveChat unarchives the chat for every member that archived it
+// without muting it, as happens when a new message arrives.
+func (c *ChatDB) UnarchiveChat(ctx context.Context, tx pgx.Tx, chatID int64, at time.Time) error {
+	const op = "storage.chat.UnarchiveChat"
+
+	q := fmt.Sprintf(`
+        UPDATE %s
+        SET archived = FALSE, updated_at = $2
+        WHERE chat_id = $1
+            AND archived
+            AND (muted_until IS NULL OR muted_until <= $2);
+	`, settingsTable)
+
+	c.log.Debug("unarchive chat query:", slog.String("query", query.QueryToString(q)))
+
+	if _, err := tx.Exec(ctx, q, chatID, at); err != nil {
+		c.log.Error("faield to unarchive chat", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"simple-chat/internal/lib/storage/query"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AddMember adds the user to the chat and keeps the chat's member count,
+// which channels report as their subscriber count, in step.
+func (c *ChatDB) AddMember(ctx context.Context, tx pgx.Tx, chatID int64, userID int64, role string, joinedAt time.Time) error {
+	const op = "storage.chat.AddMember"
+
+	q := fmt.Sprintf(`
+        WITH added AS (
+            INSERT INTO %s
+                (chat_id, user_id, role, joined_at)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (chat_id, user_id) DO NOTHING
+            RETURNING chat_id
+        )
+        UPDATE %s
+        SET member_count = member_count + 1
+        WHERE id IN (SELECT chat_id FROM added);
+	`, memberTable, chatTable)
+
+	c.log.Debug("add member query:", slog.String("query", query.QueryToString(q)))
+
+	// A conflict does not abort the surrounding transaction, so callers may
+	// treat ErrAlreadyMember as a normal outcome and carry on.
+	tag, err := tx.Exec(ctx, q, chatID, userID, role, joinedAt)
+	if err != nil {
+		c.log.Error("faield to add member", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAlreadyMember
+	}
+
+	return nil
+}
+
+// RemoveMember removes the user from the chat and decrements its member
+// count.
+func (c *ChatDB) RemoveMember(ctx context.Context, tx pgx.Tx, chatID int64, userID int64) error {
+	const op = "storage.chat.RemoveMember"
+
+	q := fmt.Sprintf(`
+        WITH removed AS (
+            DELETE FROM %s
+            WHERE chat_id = $1 AND user_id = $2
+            RETURNING chat_id
+        )
+        UPDATE %s
+        SET member_count = member_count - 1
+        WHERE id IN (SELECT chat_id FROM removed);
+	`, memberTable, chatTable)
+
+	c.log.Debug("remove member query:", slog.String("query", query.QueryToString(q)))
+
+	tag, err := tx.Exec(ctx, q, chatID, userID)
+	if err != nil {
+		c.log.Error("faield to remove member", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
+
+func (c *ChatDB) SetMemberRole(ctx context.Context, tx pgx.Tx, chatID int64, userID int64, role string) error {
+	const op = "storage.chat.SetMemberRole"
+
+	q := fmt.Sprintf(`
+        UPDATE %s
+        SET role = $3
+        WHERE chat_id = $1 AND user_id = $2;
+	`, memberTable)
+
+	c.log.Debug("set member role query:", slog.String("query", query.QueryToString(q)))
+
+	tag, err := tx.Exec(ctx, q, chatID, userID, role)
+	if err != nil {
+		c.log.Error("faield to set member role", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
+
+// GetMember returns the user's membership in the chat, locking it until the
+// transaction ends so that concurrent role changes are serialized.
+func (c *ChatDB) GetMember(ctx context.Context, tx pgx.Tx, chatID int64, userID int64) (models.ChatMember, error) {
+	const op = "storage.chat.GetMember"
+
+	q := fmt.Sprintf(`
+        SELECT user_id, role, joined_at
+        FROM %s
+        WHERE chat_id = $1 AND user_id = $2
+        FOR UPDATE;
+	`, memberTable)
+
+	c.log.Debug("get member query:", slog.String("query", query.QueryToString(q)))
+
+	var member models.ChatMember
+	err := tx.QueryRow(ctx, q, chatID, userID).Scan(&member.UserID, &member.Role, &member.JoinedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.ChatMember{}, ErrMemberNotFound
+		}
+		c.log.Error("faield to get member", sl.OpErr(op, err))
+		return models.ChatMember{}, err
+	}
+
+	return member, nil
+}
+
+func (c *ChatDB) GetMembers(ctx context.Context, tx pgx.Tx, chatID int64) ([]models.ChatMember, error) {
+	const op = "storage.chat.GetMembers"
+
+	q := fmt.Sprintf(`
+        SELECT user_id, role, joined_at
+        FROM %s
+        WHERE chat_id = $1
+        ORDER BY joined_at, user_id;
+	`, memberTable)
+
+	c.log.Debug("get members query:", slog.String("query", query.QueryToString(q)))
+
+	rows, err := tx.Query(ctx, q, chatID)
+	if err != nil {
+		c.log.Error("faield to get members", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.ChatMember
+	for rows.Next() {
+		var member models.ChatMember
+		if err := rows.Scan(&member.UserID, &member.Role, &member.JoinedAt); err != nil {
+			c.log.Error("faield to scan member", sl.OpErr(op, err))
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		c.log.Error("faield to get members", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return members, nil
+}
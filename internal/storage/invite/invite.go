@@ -0,0 +1,156 @@
+package invite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"simple-chat/internal/lib/storage/query"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type InviteDB struct {
+	log *slog.Logger
+}
+
+func NewInviteDB(log *slog.Logger) *InviteDB {
+	return &InviteDB{
+		log: log,
+	}
+}
+
+const (
+	inviteTable = "chat_invite"
+)
+
+var (
+	ErrInviteNotFound = errors.New("invite not found")
+	// ErrInviteInvalid is returned for tokens that do not exist, were
+	// revoked, expired or ran out of uses.
+	ErrInviteInvalid = errors.New("invite is invalid or expired")
+)
+
+func (i *InviteDB) CreateInvite(ctx context.Context, tx pgx.Tx, invite models.Invite) (int64, error) {
+	const op = "storage.invite.CreateInvite"
+
+	q := fmt.Sprintf(`
+        INSERT INTO %s
+            (chat_id, token, created_by, expires_at, max_uses, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id;
+	`, inviteTable)
+
+	i.log.Debug("create invite query:", slog.String("query", query.QueryToString(q)))
+
+	var inviteID int64
+	err := tx.QueryRow(ctx, q,
+		invite.ChatID, invite.Token, invite.CreatedBy, invite.ExpiresAt, invite.MaxUses, invite.CreatedAt,
+	).Scan(&inviteID)
+	if err != nil {
+		i.log.Error("faield to create invite", sl.OpErr(op, err))
+		return 0, err
+	}
+
+	return inviteID, nil
+}
+
+// GetChatInvites returns the chat's invites that were not revoked, newest
+// first.
+func (i *InviteDB) GetChatInvites(ctx context.Context, tx pgx.Tx, chatID int64) ([]models.Invite, error) {
+	const op = "storage.invite.GetChatInvites"
+
+	q := fmt.Sprintf(`
+        SELECT id, chat_id, token, created_by, expires_at, max_uses, uses, revoked_at, created_at
+        FROM %s
+        WHERE chat_id = $1 AND revoked_at IS NULL
+        ORDER BY created_at DESC;
+	`, inviteTable)
+
+	i.log.Debug("get chat invites query:", slog.String("query", query.QueryToString(q)))
+
+	rows, err := tx.Query(ctx, q, chatID)
+	if err != nil {
+		i.log.Error("faield to get chat invites", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []models.Invite
+	for rows.Next() {
+		var invite models.Invite
+		err := rows.Scan(
+			&invite.ID, &invite.ChatID, &invite.Token, &invite.CreatedBy,
+			&invite.ExpiresAt, &invite.MaxUses, &invite.Uses, &invite.RevokedAt, &invite.CreatedAt,
+		)
+		if err != nil {
+			i.log.Error("faield to scan invite", sl.OpErr(op, err))
+			return nil, err
+		}
+
+		invites = append(invites, invite)
+	}
+
+	if err := rows.Err(); err != nil {
+		i.log.Error("faield to get chat invites", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+func (i *InviteDB) RevokeInvite(ctx context.Context, tx pgx.Tx, chatID int64, inviteID int64, revokedAt time.Time) error {
+	const op = "storage.invite.RevokeInvite"
+
+	q := fmt.Sprintf(`
+        UPDATE %s
+        SET revoked_at = $3
+        WHERE id = $1 AND chat_id = $2 AND revoked_at IS NULL;
+	`, inviteTable)
+
+	i.log.Debug("revoke invite query:", slog.String("query", query.QueryToString(q)))
+
+	tag, err := tx.Exec(ctx, q, inviteID, chatID, revokedAt)
+	if err != nil {
+		i.log.Error("faield to revoke invite", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInviteNotFound
+	}
+
+	return nil
+}
+
+// RedeemInvite counts a use of a valid invite and returns its chat. The
+// check and the increment are a single statement, so concurrent redemptions
+// cannot exceed max_uses.
+func (i *InviteDB) RedeemInvite(ctx context.Context, tx pgx.Tx, token string, at time.Time) (int64, error) {
+	const op = "storage.invite.RedeemInvite"
+
+	q := fmt.Sprintf(`
+        UPDATE %s
+        SET uses = uses + 1
+        WHERE token = $1
+            AND revoked_at IS NULL
+            AND (expires_at IS NULL OR expires_at > $2)
+            AND (max_uses IS NULL OR uses < max_uses)
+        RETURNING chat_id;
+	`, inviteTable)
+
+	i.log.Debug("redeem invite query:", slog.String("query", query.QueryToString(q)))
+
+	var chatID int64
+	if err := tx.QueryRow(ctx, q, token, at).Scan(&chatID); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrInviteInvalid
+		}
+		i.log.Error("faield to redeem invite", sl.OpErr(op, err))
+		return 0, err
+	}
+
+	return chatID, nil
+}
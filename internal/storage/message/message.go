@@ -9,8 +9,11 @@ import (
 	"simple-chat/internal/domain/models"
 	"simple-chat/internal/lib/logger/sl"
 	"simple-chat/internal/lib/storage/query"
+	"simple-chat/internal/storage/chat"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type MessageDB struct {
@@ -25,6 +28,8 @@ func NewMessageDB(log *slog.Logger) *MessageDB {
 
 const (
 	messageTable = "message"
+
+	foreignKeyViolation = "23503"
 )
 
 var (
@@ -37,17 +42,21 @@ func (m *MessageDB) CreateMessage(ctx context.Context, tx pgx.Tx, message dto.Me
 
 	q := fmt.Sprintf(`
         INSERT INTO %s 
-            (chat_id, sender, text, created_at)
+            (chat_id, sender, text, created_at, flag_reason, type, event, expires_at)
         VALUES 
-            ($1, $2, $3, $4)
+            ($1, $2, $3, $4, NULLIF($5, ''), COALESCE(NULLIF($6, ''), 'text'), $7, $8)
 		RETURNING id;
 	`, messageTable)
 
 	m.log.Debug("create message query:", slog.String("query", query.QueryToString(q)))
 
 	var messageID int64
-	err := tx.QueryRow(ctx, q, message.ChatID, message.Sender, message.Text, message.CreatedAt).Scan(&messageID)
+	err := tx.QueryRow(ctx, q, message.ChatID, message.Sender, message.Text, message.CreatedAt, message.FlagReason, message.Type, message.Event, message.ExpiresAt).Scan(&messageID)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return 0, chat.ErrChatNotFound
+		}
 		m.log.Error("faield to create message", sl.OpErr(op, err))
 		return 0, err
 	}
@@ -55,20 +64,22 @@ func (m *MessageDB) CreateMessage(ctx context.Context, tx pgx.Tx, message dto.Me
 	return messageID, nil
 }
 
-func (m *MessageDB) GetMessageByID(ctx context.Context, tx pgx.Tx, messageID int64) (models.Message, error) {
+// GetMessageByID returns the message unless it has expired by at, even if
+// the sweeper has not purged it yet.
+func (m *MessageDB) GetMessageByID(ctx context.Context, tx pgx.Tx, messageID int64, at time.Time) (models.Message, error) {
 	const op = "storage.message.GetMessageByID"
 
 	q := fmt.Sprintf(`
         SELECT 
-            id, chat_id, sender, text, created_at 
+            id, chat_id, type, sender, text, created_at, event, expires_at
         FROM %s 
-        WHERE id = $1;
+        WHERE id = $1 AND (expires_at IS NULL OR expires_at > $2);
 	`, messageTable)
 
 	m.log.Debug("get message by id query:", slog.String("query", query.QueryToString(q)))
 
 	var message models.Message
-	err := tx.QueryRow(ctx, q, messageID).Scan(&message.ID, &message.ChatID, &message.Sender, &message.Text, &message.CreatedAt)
+	err := tx.QueryRow(ctx, q, messageID, at).Scan(&message.ID, &message.ChatID, &message.Type, &message.Sender, &message.Text, &message.CreatedAt, &message.Event, &message.ExpiresAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return models.Message{}, ErrMessageNotFound
@@ -80,23 +91,47 @@ func (m *MessageDB) GetMessageByID(ctx context.Context, tx pgx.Tx, messageID int
 	return message, nil
 }
 
-func (m *MessageDB) GetMessagesByChatID(ctx context.Context, tx pgx.Tx, chatID int64, limit int, offset int) ([]models.Message, error) {
+func (m *MessageDB) DeleteMessage(ctx context.Context, tx pgx.Tx, messageID int64) error {
+	const op = "storage.message.DeleteMessage"
+
+	q := fmt.Sprintf(`
+        DELETE FROM %s
+        WHERE id = $1;
+	`, messageTable)
+
+	m.log.Debug("delete message query:", slog.String("query", query.QueryToString(q)))
+
+	tag, err := tx.Exec(ctx, q, messageID)
+	if err != nil {
+		m.log.Error("faield to delete message", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMessageNotFound
+	}
+
+	return nil
+}
+
+// GetMessagesByChatID returns the chat's messages that have not expired at
+// at, newest first.
+func (m *MessageDB) GetMessagesByChatID(ctx context.Context, tx pgx.Tx, chatID int64, at time.Time, limit int, offset int) ([]models.Message, error) {
 	const op = "storage.message.GetMessagesByChatID"
 
 	q := fmt.Sprintf(`
         SELECT 
-            id, chat_id, sender, text, created_at 
+            id, chat_id, type, sender, text, created_at, event, expires_at
         FROM %s 
-        WHERE chat_id = $1
+        WHERE chat_id = $1 AND (expires_at IS NULL OR expires_at > $2)
 		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3;
+		LIMIT $3 OFFSET $4;
 	`, messageTable)
 
 	m.log.Debug("get messages by chat id query:", slog.String("query", query.QueryToString(q)))
 
 	var messages []models.Message
 
-	rows, err := tx.Query(ctx, q, chatID, limit, offset)
+	rows, err := tx.Query(ctx, q, chatID, at, limit, offset)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, ErrMessagesNotFound
@@ -107,7 +142,7 @@ func (m *MessageDB) GetMessagesByChatID(ctx context.Context, tx pgx.Tx, chatID i
 
 	for rows.Next() {
 		var message models.Message
-		err := rows.Scan(&message.ID, &message.ChatID, &message.Sender, &message.Text, &message.CreatedAt)
+		err := rows.Scan(&message.ID, &message.ChatID, &message.Type, &message.Sender, &message.Text, &message.CreatedAt, &message.Event, &message.ExpiresAt)
 		if err != nil {
 			m.log.Error("faield to scan message", sl.OpErr(op, err))
 			return nil, err
@@ -129,7 +164,7 @@ func (m *MessageDB) GetListMessagesByID(ctx context.Context, tx pgx.Tx, messages
 
 	q := fmt.Sprintf(`
         SELECT 
-            id, chat_id, sender, text, created_at 
+            id, chat_id, type, sender, text, created_at, event
         FROM %s 
         WHERE chat_id ON $1;
 	`, messageTable)
@@ -148,7 +183,7 @@ func (m *MessageDB) GetListMessagesByID(ctx context.Context, tx pgx.Tx, messages
 
 	for rows.Next() {
 		var message models.Message
-		err := rows.Scan(&message.ID, &message.ChatID, &message.Sender, &message.Text, &message.CreatedAt)
+		err := rows.Scan(&message.ID, &message.ChatID, &message.Type, &message.Sender, &message.Text, &message.CreatedAt, &message.Event)
 		if err != nil {
 			m.log.Error("faield to scan message", sl.OpErr(op, err))
 			return nil, err
@@ -164,3 +199,48 @@ func (m *MessageDB) GetListMessagesByID(ctx context.Context, tx pgx.Tx, messages
 
 	return messages, nil
 }
+
+// PurgeExpired deletes up to limit messages that expired at at and returns
+// their IDs and chats. Rows locked by another sweeper are skipped.
+func (m *MessageDB) PurgeExpired(ctx context.Context, tx pgx.Tx, at time.Time, limit int) ([]models.Message, error) {
+	const op = "storage.message.PurgeExpired"
+
+	q := fmt.Sprintf(`
+        DELETE FROM %s
+        WHERE id IN (
+            SELECT id FROM %s
+            WHERE expires_at <= $1
+            ORDER BY expires_at
+            LIMIT $2
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, chat_id;
+	`, messageTable, messageTable)
+
+	m.log.Debug("purge expired messages query:", slog.String("query", query.QueryToString(q)))
+
+	rows, err := tx.Query(ctx, q, at, limit)
+	if err != nil {
+		m.log.Error("faield to purge expired messages", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.Message
+	for rows.Next() {
+		var message models.Message
+		if err := rows.Scan(&message.ID, &message.ChatID); err != nil {
+			m.log.Error("faield to scan message", sl.OpErr(op, err))
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		m.log.Error("faield to purge expired messages", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return messages, nil
+}
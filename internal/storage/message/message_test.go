@@ -0,0 +1,135 @@
+package message
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"simple-chat/internal/lib/storage/pgtest"
+	"testing"
+	"time"
+)
+
+func TestPurgeExpiredSkipsLocked(t *testing.T) {
+	pool := pgtest.Connect(t)
+	db := NewMessageDB(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	// A fixed point far in the past keeps rows of other tests out of reach.
+	at := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		expired    int
+		live       int
+		permanent  int
+		limit      int
+		sweepers   int
+		wantPurged int
+	}{
+		{name: "batches split between sweepers", expired: 6, limit: 2, sweepers: 3, wantPurged: 6},
+		{name: "batch limit is respected", expired: 5, limit: 2, sweepers: 2, wantPurged: 4},
+		{name: "live messages are kept", expired: 2, live: 3, limit: 10, sweepers: 2, wantPurged: 2},
+		{name: "messages without ttl are kept", expired: 1, permanent: 3, limit: 10, sweepers: 1, wantPurged: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatID := pgtest.CreateChat(t, pool)
+
+			insert := func(count int, expiresAt *time.Time) {
+				for range count {
+					_, err := pool.Exec(ctx, `
+                        INSERT INTO message (chat_id, sender, text, created_at, expires_at)
+                        VALUES ($1, 1, 'text', $2, $3);
+					`, chatID, at.Add(-2*time.Hour), expiresAt)
+					if err != nil {
+						t.Fatalf("failed to insert message: %v", err)
+					}
+				}
+			}
+			expired, live := at.Add(-time.Hour), at.Add(time.Hour)
+			insert(tt.expired, &expired)
+			insert(tt.live, &live)
+			insert(tt.permanent, nil)
+
+			// Every sweeper holds its locks until all of them have purged.
+			seen := make(map[int64]bool)
+			for range tt.sweepers {
+				tx, err := pool.Begin(ctx)
+				if err != nil {
+					t.Fatalf("failed to start transaction: %v", err)
+				}
+				defer tx.Rollback(ctx)
+
+				purged, err := db.PurgeExpired(ctx, tx, at, tt.limit)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(purged) > tt.limit {
+					t.Fatalf("purged %d messages, over the limit of %d", len(purged), tt.limit)
+				}
+				for _, message := range purged {
+					if message.ChatID != chatID {
+						t.Fatalf("purged message of chat %d, want %d", message.ChatID, chatID)
+					}
+					if seen[message.ID] {
+						t.Fatalf("message %d was purged by two sweepers", message.ID)
+					}
+					seen[message.ID] = true
+				}
+			}
+
+			if len(seen) != tt.wantPurged {
+				t.Errorf("purged %d messages, want %d", len(seen), tt.wantPurged)
+			}
+		})
+	}
+}
+
+func TestGetMessageByIDHidesExpired(t *testing.T) {
+	pool := pgtest.Connect(t)
+	db := NewMessageDB(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+	at := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	chatID := pgtest.CreateChat(t, pool)
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		wantErr   error
+	}{
+		{name: "without ttl", expiresAt: nil},
+		{name: "not expired yet", expiresAt: ptr(at.Add(time.Hour))},
+		{name: "expired but not purged", expiresAt: ptr(at.Add(-time.Hour)), wantErr: ErrMessageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messageID int64
+			err := pool.QueryRow(ctx, `
+                INSERT INTO message (chat_id, sender, text, created_at, expires_at)
+                VALUES ($1, 1, 'text', $2, $3)
+                RETURNING id;
+			`, chatID, at.Add(-2*time.Hour), tt.expiresAt).Scan(&messageID)
+			if err != nil {
+				t.Fatalf("failed to insert message: %v", err)
+			}
+
+			tx, err := pool.Begin(ctx)
+			if err != nil {
+				t.Fatalf("failed to start transaction: %v", err)
+			}
+			defer tx.Rollback(ctx)
+
+			_, err = db.GetMessageByID(ctx, tx, messageID, at)
+			if err != tt.wantErr {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
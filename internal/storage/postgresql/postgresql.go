@@ -4,12 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"os"
-	"os/signal"
 	"simple-chat/internal/config"
 	"simple-chat/internal/lib/logger/sl"
 	"simple-chat/internal/lib/storage/repeateble"
-	"syscall"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -48,15 +45,5 @@ func NewConection(ctx context.Context, log *slog.Logger, cfg config.Database) (*
 		return nil, err
 	}
 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
-
-	go func() {
-		stopSignal := <-stop
-		log.Info("stoppping database connection", slog.String("op", op), slog.String("signal", stopSignal.String()))
-		pool.Close()
-		log.Info("database was stopped")
-	}()
-
 	return pool, nil
 }
@@ -0,0 +1,176 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"simple-chat/internal/domain/dto"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"simple-chat/internal/lib/storage/query"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ReportDB struct {
+	log *slog.Logger
+}
+
+func NewReportDB(log *slog.Logger) *ReportDB {
+	return &ReportDB{
+		log: log,
+	}
+}
+
+const (
+	reportTable = "report"
+)
+
+var (
+	ErrReportNotFound = errors.New("report not found")
+)
+
+func (r *ReportDB) CreateReport(ctx context.Context, tx pgx.Tx, report dto.Report) (int64, error) {
+	const op = "storage.report.CreateReport"
+
+	q := fmt.Sprintf(`
+        INSERT INTO %s
+            (reporter, chat_id, message_id, message_sender, message_text, message_created_at, reason, status, created_at)
+        VALUES
+            (NULLIF($1, 0), $2, NULLIF($3, 0), $4, $5, $6, $7, $8, $9)
+        RETURNING id;
+	`, reportTable)
+
+	r.log.Debug("create report query:", slog.String("query", query.QueryToString(q)))
+
+	var (
+		sender    *int64
+		text      *string
+		createdAt *time.Time
+	)
+	if report.Message != nil {
+		sender, text, createdAt = &report.Message.Sender, &report.Message.Text, &report.Message.CreatedAt
+	}
+
+	var reportID int64
+	err := tx.QueryRow(ctx, q,
+		report.Reporter, report.ChatID, report.MessageID, sender, text, createdAt,
+		report.Reason, models.ReportOpen, report.CreatedAt,
+	).Scan(&reportID)
+	if err != nil {
+		r.log.Error("faield to create report", sl.OpErr(op, err))
+		return 0, err
+	}
+
+	return reportID, nil
+}
+
+func (r *ReportDB) GetOpenReports(ctx context.Context, tx pgx.Tx, limit int, offset int) ([]models.Report, error) {
+	const op = "storage.report.GetOpenReports"
+
+	q := fmt.Sprintf(`
+        SELECT
+            id, COALESCE(reporter, 0), chat_id, COALESCE(message_id, 0),
+            message_sender, message_text, message_created_at,
+            reason, status, created_at
+        FROM %s
+        WHERE status = $1
+        ORDER BY created_at
+        LIMIT $2 OFFSET $3;
+	`, reportTable)
+
+	r.log.Debug("get open reports query:", slog.String("query", query.QueryToString(q)))
+
+	rows, err := tx.Query(ctx, q, models.ReportOpen, limit, offset)
+	if err != nil {
+		r.log.Error("faield to get open reports", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []models.Report
+	for rows.Next() {
+		var (
+			report    models.Report
+			sender    *int64
+			text      *string
+			createdAt *time.Time
+		)
+
+		err := rows.Scan(
+			&report.ID, &report.Reporter, &report.ChatID, &report.MessageID,
+			&sender, &text, &createdAt,
+			&report.Reason, &report.Status, &report.CreatedAt,
+		)
+		if err != nil {
+			r.log.Error("faield to scan report", sl.OpErr(op, err))
+			return nil, err
+		}
+
+		if text != nil {
+			report.Message = &models.Message{
+				ID:        report.MessageID,
+				ChatID:    report.ChatID,
+				Sender:    *sender,
+				Text:      *text,
+				CreatedAt: *createdAt,
+			}
+		}
+
+		reports = append(reports, report)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.log.Error("faield to get open reports", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// ResolveReport closes an open report. Reports that do not exist or are
+// already closed return ErrReportNotFound.
+func (r *ReportDB) ResolveReport(ctx context.Context, tx pgx.Tx, reportID int64, status string, resolution string, resolvedBy int64, resolvedAt time.Time) error {
+	const op = "storage.report.ResolveReport"
+
+	q := fmt.Sprintf(`
+        UPDATE %s
+        SET status = $1, resolution = NULLIF($2, ''), resolved_by = $3, resolved_at = $4
+        WHERE id = $5 AND status = $6;
+	`, reportTable)
+
+	r.log.Debug("resolve report query:", slog.String("query", query.QueryToString(q)))
+
+	tag, err := tx.Exec(ctx, q, status, resolution, resolvedBy, resolvedAt, reportID, models.ReportOpen)
+	if err != nil {
+		r.log.Error("faield to resolve report", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrReportNotFound
+	}
+
+	return nil
+}
+
+// ResolveMessageReports closes every open report about a message.
+func (r *ReportDB) ResolveMessageReports(ctx context.Context, tx pgx.Tx, messageID int64, resolution string, resolvedBy int64, resolvedAt time.Time) error {
+	const op = "storage.report.ResolveMessageReports"
+
+	q := fmt.Sprintf(`
+        UPDATE %s
+        SET status = $1, resolution = $2, resolved_by = $3, resolved_at = $4
+        WHERE message_id = $5 AND status = $6;
+	`, reportTable)
+
+	r.log.Debug("resolve message reports query:", slog.String("query", query.QueryToString(q)))
+
+	if _, err := tx.Exec(ctx, q, models.ReportResolved, resolution, resolvedBy, resolvedAt, messageID, models.ReportOpen); err != nil {
+		r.log.Error("faield to resolve message reports", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package scheduled
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"simple-chat/internal/lib/storage/query"
+	"simple-chat/internal/storage/chat"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ScheduledDB struct {
+	log *slog.Logger
+}
+
+func NewScheduledDB(log *slog.Logger) *ScheduledDB {
+	return &ScheduledDB{
+		log: log,
+	}
+}
+
+const (
+	scheduledTable = "scheduled_message"
+
+	foreignKeyViolation = "23503"
+)
+
+var ErrScheduledNotFound = errors.New("scheduled message not found")
+
+func (s *ScheduledDB) CreateScheduled(ctx context.Context, tx pgx.Tx, message models.ScheduledMessage) (int64, error) {
+	const op = "storage.scheduled.CreateScheduled"
+
+	q := fmt.Sprintf(`
+        INSERT INTO %s
+            (chat_id, sender, text, send_at, status, created_at)
+        VALUES ($1, $2, $3, $4, 'pending', $5)
+        RETURNING id;
+	`, scheduledTable)
+
+	s.log.Debug("create scheduled message query:", slog.String("query", query.QueryToString(q)))
+
+	var messageID int64
+	err := tx.QueryRow(ctx, q, message.ChatID, message.Sender, message.Text, message.SendAt, message.CreatedAt).Scan(&messageID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return 0, chat.ErrChatNotFound
+		}
+		s.log.Error("faield to create scheduled message", sl.OpErr(op, err))
+		return 0, err
+	}
+
+	return messageID, nil
+}
+
+// GetUserScheduled returns the sender's pending and failed scheduled
+// messages, soonest first.
+func (s *ScheduledDB) GetUserScheduled(ctx context.Context, tx pgx.Tx, sender int64) ([]models.ScheduledMessage, error) {
+	const op = "storage.scheduled.GetUserScheduled"
+
+	q := fmt.Sprintf(`
+        SELECT id, chat_id, sender, text, send_at, status, COALESCE(error, ''), created_at
+        FROM %s
+        WHERE sender = $1
+        ORDER BY send_at, id;
+	`, scheduledTable)
+
+	s.log.Debug("get user scheduled messages query:", slog.String("query", query.QueryToString(q)))
+
+	rows, err := tx.Query(ctx, q, sender)
+	if err != nil {
+		s.log.Error("faield to get scheduled messages", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.ScheduledMessage
+	for rows.Next() {
+		var message models.ScheduledMessage
+		err := rows.Scan(
+			&message.ID, &message.ChatID, &message.Sender, &message.Text,
+			&message.SendAt, &message.Status, &message.Error, &message.CreatedAt,
+		)
+		if err != nil {
+			s.log.Error("faield to scan scheduled message", sl.OpErr(op, err))
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("faield to get scheduled messages", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// UpdateScheduled changes the sender's scheduled message, leaving nil fields
+// as they are. Failed messages are queued again.
+func (s *ScheduledDB) UpdateScheduled(ctx context.Context, tx pgx.Tx, messageID int64, sender int64, text *string, sendAt *time.Time) (models.ScheduledMessage, error) {
+	const op = "storage.scheduled.UpdateScheduled"
+
+	q := fmt.Sprintf(`
+        UPDATE %s
+        SET text = COALESCE($3, text),
+            send_at = COALESCE($4, send_at),
+            status = 'pending',
+            error = NULL
+        WHERE id = $1 AND sender = $2
+        RETURNING id, chat_id, sender, text, send_at, status, COALESCE(error, ''), created_at;
+	`, scheduledTable)
+
+	s.log.Debug("update scheduled message query:", slog.String("query", query.QueryToString(q)))
+
+	var message models.ScheduledMessage
+	err := tx.QueryRow(ctx, q, messageID, sender, text, sendAt).Scan(
+		&message.ID, &message.ChatID, &message.Sender, &message.Text,
+		&message.SendAt, &message.Status, &message.Error, &message.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.ScheduledMessage{}, ErrScheduledNotFound
+		}
+		s.log.Error("faield to update scheduled message", sl.OpErr(op, err))
+		return models.ScheduledMessage{}, err
+	}
+
+	return message, nil
+}
+
+func (s *ScheduledDB) DeleteScheduled(ctx context.Context, tx pgx.Tx, messageID int64, sender int64) error {
+	const op = "storage.scheduled.DeleteScheduled"
+
+	q := fmt.Sprintf(`
+        DELETE FROM %s
+        WHERE id = $1 AND sender = $2;
+	`, scheduledTable)
+
+	s.log.Debug("delete scheduled message query:", slog.String("query", query.QueryToString(q)))
+
+	tag, err := tx.Exec(ctx, q, messageID, sender)
+	if err != nil {
+		s.log.Error("faield to delete scheduled message", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrScheduledNotFound
+	}
+
+	return nil
+}
+
+// GetDueScheduled returns the pending message that is due the longest and
+// locks it until the transaction ends. Messages locked by other instances
+// are skipped, so every message is delivered by a single instance.
+func (s *ScheduledDB) GetDueScheduled(ctx context.Context, tx pgx.Tx, at time.Time) (models.ScheduledMessage, error) {
+	const op = "storage.scheduled.GetDueScheduled"
+
+	q := fmt.Sprintf(`
+        SELECT id, chat_id, sender, text, send_at, status, COALESCE(error, ''), created_at
+        FROM %s
+        WHERE status = 'pending' AND send_at <= $1
+        ORDER BY send_at, id
+        LIMIT 1
+        FOR UPDATE SKIP LOCKED;
+	`, scheduledTable)
+
+	s.log.Debug("get due scheduled message query:", slog.String("query", query.QueryToString(q)))
+
+	var message models.ScheduledMessage
+	err := tx.QueryRow(ctx, q, at).Scan(
+		&message.ID, &message.ChatID, &message.Sender, &message.Text,
+		&message.SendAt, &message.Status, &message.Error, &message.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.ScheduledMessage{}, ErrScheduledNotFound
+		}
+		s.log.Error("faield to get due scheduled message", sl.OpErr(op, err))
+		return models.ScheduledMessage{}, err
+	}
+
+	return message, nil
+}
+
+func (s *ScheduledDB) FailScheduled(ctx context.Context, tx pgx.Tx, messageID int64, reason string) error {
+	const op = "storage.scheduled.FailScheduled"
+
+	q := fmt.Sprintf(`
+        UPDATE %s
+        SET status = 'failed', error = $2
+        WHERE id = $1;
+	`, scheduledTable)
+
+	s.log.Debug("fail scheduled message query:", slog.String("query", query.QueryToString(q)))
+
+	if _, err := tx.Exec(ctx, q, messageID, reason); err != nil {
+		s.log.Error("faield to fail scheduled message", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package scheduled
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"simple-chat/internal/lib/storage/pgtest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestGetDueScheduledSkipsLocked(t *testing.T) {
+	pool := pgtest.Connect(t)
+	db := NewScheduledDB(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	// A fixed point far in the past keeps rows of other tests out of reach.
+	at := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		due       int
+		notDue    int
+		failed    int
+		instances int
+		wantFound int
+	}{
+		{name: "one message per instance", due: 3, instances: 3, wantFound: 3},
+		{name: "more instances than messages", due: 2, instances: 4, wantFound: 2},
+		{name: "future messages are skipped", due: 1, notDue: 2, instances: 3, wantFound: 1},
+		{name: "failed messages are skipped", due: 1, failed: 2, instances: 3, wantFound: 1},
+		{name: "nothing due", notDue: 1, instances: 2, wantFound: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatID := pgtest.CreateChat(t, pool)
+			t.Cleanup(func() {
+				pool.Exec(ctx, `DELETE FROM scheduled_message WHERE chat_id = $1;`, chatID)
+			})
+
+			insert := func(count int, sendAt time.Time, status string) {
+				for range count {
+					_, err := pool.Exec(ctx, `
+                        INSERT INTO scheduled_message (chat_id, sender, text, send_at, status, created_at)
+                        VALUES ($1, 1, 'text', $2, $3, $2);
+					`, chatID, sendAt, status)
+					if err != nil {
+						t.Fatalf("failed to insert scheduled message: %v", err)
+					}
+				}
+			}
+			insert(tt.due, at.Add(-time.Hour), "pending")
+			insert(tt.notDue, at.Add(time.Hour), "pending")
+			insert(tt.failed, at.Add(-time.Hour), "failed")
+
+			// Every instance holds its lock until all of them have looked.
+			seen := make(map[int64]bool)
+			found := 0
+			for range tt.instances {
+				tx, err := pool.Begin(ctx)
+				if err != nil {
+					t.Fatalf("failed to start transaction: %v", err)
+				}
+				defer tx.Rollback(ctx)
+
+				message, err := db.GetDueScheduled(ctx, tx, at)
+				if errors.Is(err, ErrScheduledNotFound) {
+					continue
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if message.ChatID != chatID {
+					t.Fatalf("got message of chat %d, want %d", message.ChatID, chatID)
+				}
+				if seen[message.ID] {
+					t.Fatalf("message %d was handed to two instances", message.ID)
+				}
+				seen[message.ID] = true
+				found++
+			}
+
+			if found != tt.wantFound {
+				t.Errorf("found %d messages, want %d", found, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestGetDueScheduledReleasesOnRollback(t *testing.T) {
+	pool := pgtest.Connect(t)
+	db := NewScheduledDB(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+	at := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	chatID := pgtest.CreateChat(t, pool)
+	t.Cleanup(func() {
+		pool.Exec(ctx, `DELETE FROM scheduled_message WHERE chat_id = $1;`, chatID)
+	})
+	_, err := pool.Exec(ctx, `
+        INSERT INTO scheduled_message (chat_id, sender, text, send_at, created_at)
+        VALUES ($1, 1, 'text', $2, $2);
+	`, chatID, at.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("failed to insert scheduled message: %v", err)
+	}
+
+	get := func(tx pgx.Tx) (int64, error) {
+		message, err := db.GetDueScheduled(ctx, tx, at)
+		return message.ID, err
+	}
+
+	first, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+	locked, err := get(first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// An instance that fails mid-delivery leaves the message for the next.
+	first.Rollback(ctx)
+
+	second, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+	defer second.Rollback(ctx)
+
+	retried, err := get(second)
+	if err != nil {
+		t.Fatalf("message was not released by the rollback: %v", err)
+	}
+	if retried != locked {
+		t.Errorf("got message %d, want %d", retried, locked)
+	}
+}
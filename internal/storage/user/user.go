@@ -0,0 +1,267 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"simple-chat/internal/domain/models"
+	"simple-chat/internal/lib/logger/sl"
+	"simple-chat/internal/lib/storage/query"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type UserDB struct {
+	log *slog.Logger
+}
+
+func NewUserDB(log *slog.Logger) *UserDB {
+	return &UserDB{
+		log: log,
+	}
+}
+
+const (
+	userTable       = "user_profile"
+	suspensionTable = "user_suspension"
+	blockTable      = "user_block"
+)
+
+var (
+	ErrNotSuspended = errors.New("user is not suspended")
+	ErrNotBlocked   = errors.New("user is not blocked")
+)
+
+func (u *UserDB) SaveUser(ctx context.Context, tx pgx.Tx, user models.User, updatedAt time.Time) error {
+	const op = "storage.user.SaveUser"
+
+	q := fmt.Sprintf(`
+        INSERT INTO %s
+            (id, email, name, updated_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (id) DO UPDATE
+        SET email = EXCLUDED.email, name = EXCLUDED.name, updated_at = EXCLUDED.updated_at
+        WHERE %s.email <> EXCLUDED.email OR %s.name <> EXCLUDED.name;
+	`, userTable, userTable, userTable)
+
+	u.log.Debug("save user query:", slog.String("query", query.QueryToString(q)))
+
+	if _, err := tx.Exec(ctx, q, user.UserID, user.Email, user.Name, updatedAt); err != nil {
+		u.log.Error("faield to save user", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+func (u *UserDB) GetUsers(ctx context.Context, tx pgx.Tx, userIDs []int64) ([]models.User, error) {
+	const op = "storage.user.GetUsers"
+
+	q := fmt.Sprintf(`
+        SELECT id, email, name
+        FROM %s
+        WHERE id = ANY($1);
+	`, userTable)
+
+	u.log.Debug("get users query:", slog.String("query", query.QueryToString(q)))
+
+	var users []models.User
+
+	rows, err := tx.Query(ctx, q, userIDs)
+	if err != nil {
+		u.log.Error("faield to get users", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.UserID, &user.Email, &user.Name); err != nil {
+			u.log.Error("faield to scan user", sl.OpErr(op, err))
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		u.log.Error("faield to get users", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// SuspendUser suspends the user, replacing any existing suspension.
+func (u *UserDB) SuspendUser(ctx context.Context, tx pgx.Tx, suspension models.Suspension) error {
+	const op = "storage.user.SuspendUser"
+
+	q := fmt.Sprintf(`
+        INSERT INTO %s
+            (user_id, reason, suspended_by, until, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (user_id) DO UPDATE
+        SET reason = EXCLUDED.reason, suspended_by = EXCLUDED.suspended_by,
+            until = EXCLUDED.until, created_at = EXCLUDED.created_at;
+	`, suspensionTable)
+
+	u.log.Debug("suspend user query:", slog.String("query", query.QueryToString(q)))
+
+	_, err := tx.Exec(ctx, q, suspension.UserID, suspension.Reason, suspension.SuspendedBy, suspension.Until, suspension.CreatedAt)
+	if err != nil {
+		u.log.Error("faield to suspend user", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+func (u *UserDB) LiftSuspension(ctx context.Context, tx pgx.Tx, userID int64) error {
+	const op = "storage.user.LiftSuspension"
+
+	q := fmt.Sprintf(`
+        DELETE FROM %s
+        WHERE user_id = $1;
+	`, suspensionTable)
+
+	u.log.Debug("lift suspension query:", slog.String("query", query.QueryToString(q)))
+
+	tag, err := tx.Exec(ctx, q, userID)
+	if err != nil {
+		u.log.Error("faield to lift suspension", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotSuspended
+	}
+
+	return nil
+}
+
+// IsSuspended reports whether the user has a suspension in effect at the
+// given time.
+func (u *UserDB) IsSuspended(ctx context.Context, tx pgx.Tx, userID int64, at time.Time) (bool, error) {
+	const op = "storage.user.IsSuspended"
+
+	q := fmt.Sprintf(`
+        SELECT EXISTS (
+            SELECT 1 FROM %s
+            WHERE user_id = $1 AND (until IS NULL OR until > $2)
+        );
+	`, suspensionTable)
+
+	u.log.Debug("is suspended query:", slog.String("query", query.QueryToString(q)))
+
+	var suspended bool
+	if err := tx.QueryRow(ctx, q, userID, at).Scan(&suspended); err != nil {
+		u.log.Error("faield to check suspension", sl.OpErr(op, err))
+		return false, err
+	}
+
+	return suspended, nil
+}
+
+// BlockUser records that blocker blocked blocked; blocking twice is a no-op.
+func (u *UserDB) BlockUser(ctx context.Context, tx pgx.Tx, blocker int64, blocked int64, createdAt time.Time) error {
+	const op = "storage.user.BlockUser"
+
+	q := fmt.Sprintf(`
+        INSERT INTO %s
+            (blocker, blocked, created_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (blocker, blocked) DO NOTHING;
+	`, blockTable)
+
+	u.log.Debug("block user query:", slog.String("query", query.QueryToString(q)))
+
+	if _, err := tx.Exec(ctx, q, blocker, blocked, createdAt); err != nil {
+		u.log.Error("faield to block user", sl.OpErr(op, err))
+		return err
+	}
+
+	return nil
+}
+
+func (u *UserDB) UnblockUser(ctx context.Context, tx pgx.Tx, blocker int64, blocked int64) error {
+	const op = "storage.user.UnblockUser"
+
+	q := fmt.Sprintf(`
+        DELETE FROM %s
+        WHERE blocker = $1 AND blocked = $2;
+	`, blockTable)
+
+	u.log.Debug("unblock user query:", slog.String("query", query.QueryToString(q)))
+
+	tag, err := tx.Exec(ctx, q, blocker, blocked)
+	if err != nil {
+		u.log.Error("faield to unblock user", sl.OpErr(op, err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotBlocked
+	}
+
+	return nil
+}
+
+func (u *UserDB) GetBlockedUsers(ctx context.Context, tx pgx.Tx, blocker int64) ([]models.Block, error) {
+	const op = "storage.user.GetBlockedUsers"
+
+	q := fmt.Sprintf(`
+        SELECT blocked, created_at
+        FROM %s
+        WHERE blocker = $1
+        ORDER BY created_at DESC;
+	`, blockTable)
+
+	u.log.Debug("get blocked users query:", slog.String("query", query.QueryToString(q)))
+
+	rows, err := tx.Query(ctx, q, blocker)
+	if err != nil {
+		u.log.Error("faield to get blocked users", sl.OpErr(op, err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []models.Block
+	for rows.Next() {
+		var block models.Block
+		if err := rows.Scan(&block.UserID, &block.CreatedAt); err != nil {
+			u.log.Error("faield to scan blocked user", sl.OpErr(op, err))
+			return nil, err
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	if err := rows.Err(); err != nil {
+		u.log.Error("faield to get blocked users", sl.OpErr(op, err))
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// IsBlocked reports whether blocker has blocked blocked.
+func (u *UserDB) IsBlocked(ctx context.Context, tx pgx.Tx, blocker int64, blocked int64) (bool, error) {
+	const op = "storage.user.IsBlocked"
+
+	q := fmt.Sprintf(`
+        SELECT EXISTS (
+            SELECT 1 FROM %s
+            WHERE blocker = $1 AND blocked = $2
+        );
+	`, blockTable)
+
+	u.log.Debug("is blocked query:", slog.String("query", query.QueryToString(q)))
+
+	var isBlocked bool
+	if err := tx.QueryRow(ctx, q, blocker, blocked).Scan(&isBlocked); err != nil {
+		u.log.Error("faield to check block", sl.OpErr(op, err))
+		return false, err
+	}
+
+	return isBlocked, nil
+}
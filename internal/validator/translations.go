@@ -0,0 +1,117 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	ruTranslations "github.com/go-playground/validator/v10/translations/ru"
+	"golang.org/x/text/language"
+)
+
+const summaryKey = "validation_error"
+
+var (
+	universal = ut.New(en.New(), en.New(), ru.New())
+
+	// supported lists the bundled languages; the first one is the default.
+	supported = []language.Tag{language.English, language.Russian}
+	matcher   = language.NewMatcher(supported)
+)
+
+func registerTranslations(v *validator.Validate) {
+	bundles := []struct {
+		locale   string
+		register func(*validator.Validate, ut.Translator) error
+		summary  string
+		// extra covers tags the bundled translations lack.
+		extra map[string]string
+	}{
+		{"en", enTranslations.RegisterDefaultTranslations, "validation error: {0}", map[string]string{
+			"http_url": "{0} must be an http or https URL",
+		}},
+		{"ru", ruTranslations.RegisterDefaultTranslations, "ошибка валидации: {0}", map[string]string{
+			"required_without": "{0} обязательное поле",
+			"http_url":         "{0} должен быть http или https URL",
+		}},
+	}
+
+	for _, bundle := range bundles {
+		trans, _ := universal.GetTranslator(bundle.locale)
+		if err := bundle.register(v, trans); err != nil {
+			panic(fmt.Sprintf("validator: failed to register %s translations: %s", bundle.locale, err))
+		}
+		if err := trans.Add(summaryKey, bundle.summary, false); err != nil {
+			panic(fmt.Sprintf("validator: failed to register %s summary: %s", bundle.locale, err))
+		}
+		for tag, text := range bundle.extra {
+			if err := v.RegisterTranslation(tag, trans, addTranslation(tag, text), translateField); err != nil {
+				panic(fmt.Sprintf("validator: failed to register %s %s translation: %s", bundle.locale, tag, err))
+			}
+		}
+	}
+}
+
+func addTranslation(tag string, text string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(tag, text, false)
+	}
+}
+
+func translateField(trans ut.Translator, fieldErr validator.FieldError) string {
+	text, err := trans.T(fieldErr.Tag(), fieldErr.Field())
+	if err != nil {
+		return fieldErr.Error()
+	}
+	return text
+}
+
+// Translator localizes validation errors into a single language.
+type Translator struct {
+	trans ut.Translator
+}
+
+// TranslatorFor picks the bundled language that best matches an
+// Accept-Language header, falling back to English.
+func TranslatorFor(acceptLanguage string) Translator {
+	tag, _ := language.MatchStrings(matcher, acceptLanguage)
+	base, _ := tag.Base()
+
+	trans, found := universal.GetTranslator(base.String())
+	if !found {
+		return defaultTranslator()
+	}
+	return Translator{trans: trans}
+}
+
+func defaultTranslator() Translator {
+	return Translator{trans: universal.GetFallback()}
+}
+
+// Errors returns a copy of errs with the messages translated.
+func (t Translator) Errors(errs Errors) Errors {
+	translated := make(Errors, len(errs))
+	for idx, fieldErr := range errs {
+		if fieldErr.source != nil {
+			fieldErr.Message = t.message(fieldErr.source)
+		}
+		translated[idx] = fieldErr
+	}
+	return translated
+}
+
+// Summary joins the messages of errs into a single sentence.
+func (t Translator) Summary(errs Errors) string {
+	summary, err := t.trans.T(summaryKey, errs.Error())
+	if err != nil {
+		return errs.Error()
+	}
+	return summary
+}
+
+func (t Translator) message(fieldErr validator.FieldError) string {
+	return fieldErr.Translate(t.trans)
+}
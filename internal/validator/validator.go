@@ -1,38 +1,100 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
-func Validate(model interface{}) string {
-	var errMsgs []string
+// FieldError describes a single failed validation rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
 
-	validate := validator.New()
-	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
-		return field.Tag.Get("json")
+	// source is kept so the message can be translated to another language.
+	source validator.FieldError
+}
+
+// Errors holds every field that failed validation.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		msgs = append(msgs, fieldErr.Message)
+	}
+	return strings.Join(msgs, ", ")
+}
+
+var validate = newValidate()
+
+func newValidate() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		return name
 	})
+	if err := v.RegisterValidation("http_url", isHTTPURL); err != nil {
+		panic(fmt.Sprintf("validator: failed to register http_url: %s", err))
+	}
+	registerTranslations(v)
+	return v
+}
+
+// isHTTPURL accepts absolute http and https URLs with a host.
+func isHTTPURL(fl validator.FieldLevel) bool {
+	u, err := url.Parse(fl.Field().String())
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
 
+// Validate checks the model against its validate tags and returns Errors
+// when any of them fail. Messages are in the default language; use a
+// Translator to localize them.
+func Validate(model interface{}) error {
 	err := validate.Struct(model)
+	if err == nil {
+		return nil
+	}
 
-	if err != nil {
-		validErr := err.(validator.ValidationErrors)
-		for _, errMsg := range validErr {
-			switch errMsg.ActualTag() {
-			case "required":
-				errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required", errMsg.Field()))
-			case "min":
-				errMsgs = append(errMsgs, fmt.Sprintf("field %s must be at least %s", errMsg.Field(), errMsg.Param()))
-			case "email":
-				errMsgs = append(errMsgs, fmt.Sprintf("field %s must be a valid email", errMsg.Field()))
-			default:
-				errMsgs = append(errMsgs, fmt.Sprintf("field %s is not valid", errMsg.Field()))
-			}
-		}
-		return strings.Join(errMsgs, ", ")
+	var validErr validator.ValidationErrors
+	if !errors.As(err, &validErr) {
+		return err
 	}
-	return ""
+
+	trans := defaultTranslator()
+	fieldErrs := make(Errors, 0, len(validErr))
+	for _, errMsg := range validErr {
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   errMsg.Field(),
+			Rule:    errMsg.ActualTag(),
+			Param:   errMsg.Param(),
+			Message: trans.message(errMsg),
+			source:  errMsg,
+		})
+	}
+	return fieldErrs
+}
+
+// Var validates a single value against tag as if it were a struct field
+// named field, so the errors carry the field name just like Validate. It is
+// meant for rules whose parameters are only known at runtime.
+func Var(field string, value interface{}, tag string) error {
+	typ := reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: reflect.TypeOf(value),
+		Tag:  reflect.StructTag(fmt.Sprintf(`json:%q validate:%q`, field, tag)),
+	}})
+
+	model := reflect.New(typ)
+	model.Elem().Field(0).Set(reflect.ValueOf(value))
+	return Validate(model.Interface())
 }
@@ -0,0 +1,4 @@
+DELETE FROM message WHERE chat_id IN (SELECT id FROM chat WHERE type = 'channel');
+DELETE FROM chat WHERE type = 'channel';
+
+ALTER TABLE chat DROP COLUMN IF EXISTS member_count;
\ No newline at end of file
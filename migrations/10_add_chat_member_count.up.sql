@@ -0,0 +1,3 @@
+ALTER TABLE chat ADD COLUMN IF NOT EXISTS member_count INTEGER NOT NULL DEFAULT 0;
+
+UPDATE chat SET member_count = (SELECT COUNT(*) FROM chat_member m WHERE m.chat_id = chat.id);
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS scheduled_message
+(
+    id SERIAL PRIMARY KEY,
+    chat_id INTEGER NOT NULL REFERENCES chat(id) ON DELETE CASCADE,
+    sender BIGINT NOT NULL,
+    text TEXT NOT NULL,
+    send_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    error TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_scheduled_message_sender ON scheduled_message(sender);
+CREATE INDEX IF NOT EXISTS idx_scheduled_message_send_at ON scheduled_message(send_at) WHERE status = 'pending';
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_message_expires_at;
+ALTER TABLE message DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE chat DROP COLUMN IF EXISTS message_ttl;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE chat ADD COLUMN IF NOT EXISTS message_ttl INTEGER;
+
+ALTER TABLE message ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_message_expires_at ON message(expires_at) WHERE expires_at IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_profile;
\ No newline at end of file
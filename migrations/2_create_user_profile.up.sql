@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_profile
+(
+    id BIGINT PRIMARY KEY UNIQUE,
+    email TEXT NOT NULL,
+    name TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_message_flagged;
+ALTER TABLE message DROP COLUMN IF EXISTS flag_reason;
\ No newline at end of file
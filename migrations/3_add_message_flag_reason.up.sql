@@ -0,0 +1,2 @@
+ALTER TABLE message ADD COLUMN IF NOT EXISTS flag_reason TEXT;
+CREATE INDEX IF NOT EXISTS idx_message_flagged ON message(id) WHERE flag_reason IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_suspension;
+DROP TABLE IF EXISTS report;
\ No newline at end of file
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS report
+(
+    id SERIAL PRIMARY KEY UNIQUE,
+    reporter BIGINT,
+    chat_id INTEGER NOT NULL REFERENCES chat(id) ON DELETE CASCADE,
+    message_id INTEGER REFERENCES message(id) ON DELETE SET NULL,
+    message_sender BIGINT,
+    message_text TEXT,
+    message_created_at TIMESTAMP,
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    resolution TEXT,
+    resolved_by BIGINT,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_report_open ON report(created_at) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_report_message_id ON report(message_id);
+
+CREATE TABLE IF NOT EXISTS user_suspension
+(
+    user_id BIGINT PRIMARY KEY UNIQUE,
+    reason TEXT NOT NULL,
+    suspended_by BIGINT NOT NULL,
+    until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
\ No newline at end of file
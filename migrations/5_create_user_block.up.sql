@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_block
+(
+    blocker BIGINT NOT NULL,
+    blocked BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker, blocked)
+);
+CREATE INDEX IF NOT EXISTS idx_user_block_blocked ON user_block(blocked);
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS chat_settings
+(
+    chat_id INTEGER NOT NULL REFERENCES chat(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    muted_until TIMESTAMP,
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    pinned_order INTEGER,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chat_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_chat_settings_user_id ON chat_settings(user_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE chat DROP COLUMN IF EXISTS avatar_url;
+ALTER TABLE chat DROP COLUMN IF EXISTS description;
+ALTER TABLE chat DROP COLUMN IF EXISTS title;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE chat ADD COLUMN IF NOT EXISTS title TEXT;
+ALTER TABLE chat ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE chat ADD COLUMN IF NOT EXISTS avatar_url TEXT;
\ No newline at end of file
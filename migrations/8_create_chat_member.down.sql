@@ -0,0 +1,10 @@
+ALTER TABLE message DROP COLUMN IF EXISTS event;
+ALTER TABLE message DROP COLUMN IF EXISTS type;
+
+DROP TABLE IF EXISTS chat_member;
+
+DELETE FROM message WHERE chat_id IN (SELECT id FROM chat WHERE type <> 'direct');
+DELETE FROM chat WHERE type <> 'direct';
+ALTER TABLE chat ALTER COLUMN second_user_id SET NOT NULL;
+ALTER TABLE chat ALTER COLUMN first_user_id SET NOT NULL;
+ALTER TABLE chat DROP COLUMN IF EXISTS type;
\ No newline at end of file
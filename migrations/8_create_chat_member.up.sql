@@ -0,0 +1,24 @@
+ALTER TABLE chat ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'direct';
+ALTER TABLE chat ALTER COLUMN first_user_id DROP NOT NULL;
+ALTER TABLE chat ALTER COLUMN second_user_id DROP NOT NULL;
+
+CREATE TABLE IF NOT EXISTS chat_member
+(
+    chat_id INTEGER NOT NULL REFERENCES chat(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'member',
+    joined_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chat_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_chat_member_user_id ON chat_member(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_member_owner ON chat_member(chat_id) WHERE role = 'owner';
+
+INSERT INTO chat_member (chat_id, user_id, role, joined_at)
+SELECT id, first_user_id, 'member', updated_at FROM chat
+ON CONFLICT DO NOTHING;
+INSERT INTO chat_member (chat_id, user_id, role, joined_at)
+SELECT id, second_user_id, 'member', updated_at FROM chat
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE message ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'text';
+ALTER TABLE message ADD COLUMN IF NOT EXISTS event JSONB;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS chat_invite;
\ No newline at end of file
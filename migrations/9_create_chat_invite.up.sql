@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS chat_invite
+(
+    id SERIAL PRIMARY KEY,
+    chat_id INTEGER NOT NULL REFERENCES chat(id) ON DELETE CASCADE,
+    token TEXT NOT NULL UNIQUE,
+    created_by BIGINT NOT NULL,
+    expires_at TIMESTAMP,
+    max_uses INTEGER,
+    uses INTEGER NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_chat_invite_chat_id ON chat_invite(chat_id);
\ No newline at end of file